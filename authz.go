@@ -0,0 +1,114 @@
+package hrt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Principal describes an authenticated entity making a request.
+type Principal interface {
+	// Subject returns the identifier of the principal.
+	Subject() string
+	// HasScope returns true if the principal was granted the given scope.
+	HasScope(scope string) bool
+	// HasRole returns true if the principal holds the given role.
+	HasRole(role string) bool
+}
+
+// WithPrincipal returns a new context with the given principal. Authentication
+// middlewares should call this so that Require and RequireRole work.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey, p)
+}
+
+// PrincipalFromContext returns the principal in the given context. False is
+// returned if the request was not authenticated.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey).(Principal)
+	return p, ok
+}
+
+// Require creates a middleware that only allows requests whose principal was
+// granted all of the given scopes. Requests without a principal are rejected
+// with a 401 error, and requests missing a scope are rejected with a 403 error.
+// The scopes are recorded into the route's RouteInfo.
+//
+// # Example
+//
+//	r.With(hrt.Require("users:write")).Post("/users", hrt.Wrap(handleCreateUser))
+func Require(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authzHandler{next: next, scopes: scopes}
+	}
+}
+
+// RequireRole is like Require, except it checks for roles instead of scopes.
+// All of the given roles must be held by the principal.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authzHandler{next: next, roles: roles}
+	}
+}
+
+type authzHandler struct {
+	next   http.Handler
+	scopes []string
+	roles  []string
+}
+
+var _ RouteAnnotator = authzHandler{}
+
+func (h authzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorize(w, r); err != nil {
+		OptsFromContext(r.Context()).ErrorWriter.WriteError(w, err)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func (h authzHandler) authorize(w http.ResponseWriter, r *http.Request) error {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return NewHTTPError(http.StatusUnauthorized, "unauthenticated")
+	}
+
+	for _, scope := range h.scopes {
+		if !p.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				"Bearer error=\"insufficient_scope\", scope=%q", strings.Join(h.scopes, " ")))
+			return WrapHTTPError(http.StatusForbidden, fmt.Errorf("missing scope %q", scope))
+		}
+	}
+
+	for _, role := range h.roles {
+		if !p.HasRole(role) {
+			return WrapHTTPError(http.StatusForbidden, fmt.Errorf("missing role %q", role))
+		}
+	}
+
+	return nil
+}
+
+func (h authzHandler) AnnotateRoute(ri *RouteInfo) {
+	ri.Scopes = appendUnique(ri.Scopes, h.scopes...)
+	ri.Roles = appendUnique(ri.Roles, h.roles...)
+}
+
+func (h authzHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+func appendUnique(dst []string, src ...string) []string {
+outer:
+	for _, s := range src {
+		for _, d := range dst {
+			if d == s {
+				continue outer
+			}
+		}
+		dst = append(dst, s)
+	}
+	return dst
+}
@@ -0,0 +1,88 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scope := r.Header.Get("X-Scope"); scope != "" {
+				ctx := WithPrincipal(r.Context(), JWTClaims{Subject: "user", Scope: scope}.Principal())
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.With(Require("users:read")).Get("/users", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+	r.With(Require("users:write"), RequireRole("admin")).Post("/users", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+	r.With(RequireRole("admin")).Delete("/users", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+
+	tests := []struct {
+		method string
+		scope  string
+		status int
+		body   string
+	}{
+		{"GET", "", 401, `{"error":"401: unauthenticated"}`},
+		{"GET", "users:write", 403, `{"error":"403: missing scope \"users:read\""}`},
+		{"GET", "users:read users:write", 200, ``},
+		{"POST", "users:write", 403, `{"error":"403: missing role \"admin\""}`},
+		{"DELETE", "users:write", 403, `{"error":"403: missing role \"admin\""}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.scope, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/users", nil)
+			req.Header.Set("X-Scope", test.scope)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+		})
+	}
+
+	routes, err := Routes(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var security []any
+	for _, route := range routes {
+		if _, ok := route.Introspect(); !ok {
+			t.Errorf("route %s %s is not introspectable", route.Method, route.Pattern)
+		}
+		security = append(security, route.OpenAPISecurity("oauth2"))
+	}
+
+	expect := []any{
+		[]map[string][]string{{"oauth2": {}}},
+		[]map[string][]string{{"oauth2": {"users:read"}}},
+		[]map[string][]string{{"oauth2": {"users:write"}}},
+	}
+	if !reflect.DeepEqual(security, expect) {
+		t.Errorf("unexpected security requirements:\n"+
+			"expected: %v\n"+
+			"got:      %v", expect, security)
+	}
+}
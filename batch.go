@@ -0,0 +1,247 @@
+package hrt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+)
+
+// DefaultBatchMaxRequests is the default maximum number of sub-requests in a
+// single batch request.
+const DefaultBatchMaxRequests = 100
+
+// BatchOpts contains options for NewBatchHandler.
+type BatchOpts struct {
+	// MaxRequests is the maximum number of sub-requests in a batch. If zero,
+	// DefaultBatchMaxRequests is used.
+	MaxRequests int
+	// Concurrency is the maximum number of sub-requests that are dispatched
+	// at once. If zero or one, sub-requests are dispatched sequentially in
+	// order.
+	Concurrency int
+}
+
+// BatchRequest is a sub-request within a batch request.
+type BatchRequest struct {
+	// Method is the HTTP method of the sub-request.
+	Method string `json:"method"`
+	// Path is the path of the sub-request, including the query string. It
+	// must be absolute.
+	Path string `json:"path"`
+	// Headers contains additional headers for the sub-request. The headers of
+	// the batch request, such as Authorization, are inherited, except for
+	// Accept-Encoding and conditional headers such as If-None-Match, which
+	// only apply to the batch request itself.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the JSON body of the sub-request.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a BatchRequest.
+type BatchResponse struct {
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+	// Headers contains the headers of the response. Repeated headers such as
+	// Set-Cookie keep all of their values.
+	Headers http.Header `json:"headers,omitempty"`
+	// Body is the body of the response. If the response is not JSON, then it
+	// is encoded as a JSON string.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// NewBatchHandler creates a handler that accepts an array of BatchRequests and
+// returns an array of BatchResponses in the same order. Each sub-request is
+// dispatched in-process through h, which is usually the root router, so all
+// middlewares and Opts apply as if the sub-request was made directly.
+//
+// # Example
+//
+//	r := hrt.NewRouter(hrt.DefaultOpts)
+//	r.Get("/users/{id}", hrt.Wrap(handleGetUser))
+//	r.Post("/batch", hrt.NewBatchHandler(r, hrt.BatchOpts{Concurrency: 4}))
+func NewBatchHandler(h http.Handler, opts BatchOpts) http.Handler {
+	b := batchHandler{h, opts}
+	return Wrap(b.serve)
+}
+
+type batchHandler struct {
+	h    http.Handler
+	opts BatchOpts
+}
+
+func (b batchHandler) serve(ctx context.Context, reqs []BatchRequest) ([]BatchResponse, error) {
+	if ctx.Value(batchCtxKey) != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, "batch requests cannot be nested")
+	}
+
+	max := b.opts.MaxRequests
+	if max == 0 {
+		max = DefaultBatchMaxRequests
+	}
+	if len(reqs) > max {
+		return nil, WrapHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Errorf("too many requests in batch (max %d)", max))
+	}
+
+	for i, req := range reqs {
+		if req.Method == "" || !strings.HasPrefix(req.Path, "/") {
+			return nil, WrapHTTPError(http.StatusBadRequest,
+				fmt.Errorf("request %d: method and absolute path are required", i))
+		}
+	}
+
+	parent := RequestFromContext(ctx)
+	resps := make([]BatchResponse, len(reqs))
+
+	if b.opts.Concurrency <= 1 {
+		for i, req := range reqs {
+			resps[i] = b.dispatch(ctx, parent, req)
+		}
+		return resps, nil
+	}
+
+	sema := make(chan struct{}, b.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		sema <- struct{}{}
+		wg.Add(1)
+
+		go func(i int, req BatchRequest) {
+			defer func() { <-sema }()
+			defer wg.Done()
+			resps[i] = b.dispatch(ctx, parent, req)
+		}(i, req)
+	}
+
+	wg.Wait()
+	return resps, nil
+}
+
+// dispatch dispatches a single sub-request. A panicking sub-request results in
+// a 500 response rather than taking down the whole server, since sub-requests
+// may run in their own goroutines.
+func (b batchHandler) dispatch(ctx context.Context, parent *http.Request, req BatchRequest) (resp BatchResponse) {
+	defer func() {
+		if v := recover(); v != nil {
+			resp = batchErrorResponse(ctx, NewHTTPError(http.StatusInternalServerError, "internal server error"))
+		}
+	}()
+
+	// Drop the parent's routing context so that the router routes the
+	// sub-request from scratch.
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, nil)
+	ctx = context.WithValue(ctx, batchCtxKey, true)
+
+	sub, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return batchErrorResponse(ctx, WrapHTTPError(http.StatusBadRequest, errors.Wrap(err, "invalid request")))
+	}
+
+	sub.Header = parent.Header.Clone()
+	for _, k := range batchParentOnlyHeaders {
+		sub.Header.Del(k)
+	}
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Headers {
+		sub.Header.Set(k, v)
+	}
+
+	sub.Host = parent.Host
+	sub.RemoteAddr = parent.RemoteAddr
+	sub.TLS = parent.TLS
+
+	rec := newBatchRecorder()
+	b.h.ServeHTTP(rec, sub)
+	return rec.response()
+}
+
+// batchParentOnlyHeaders are the headers of the batch request that are not
+// inherited by sub-requests. Sub-responses are embedded into the batch
+// response, so they must neither be compressed nor be conditional on the
+// validators that the client sent for the batch response.
+var batchParentOnlyHeaders = []string{
+	"Content-Length",
+	"Content-Encoding",
+	"Accept-Encoding",
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+	"If-Range",
+}
+
+// batchErrorResponse returns the response of a sub-request that failed before
+// or while it was served. The error is written using the ErrorWriter of the
+// batch request's Opts, so that it looks like any other error.
+func batchErrorResponse(ctx context.Context, err error) BatchResponse {
+	rec := newBatchRecorder()
+	OptsFromContext(ctx).ErrorWriter.WriteError(rec, err)
+	return rec.response()
+}
+
+// batchRecorder records a sub-request's response.
+type batchRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) response() BatchResponse {
+	resp := BatchResponse{
+		Status:  r.status,
+		Headers: make(http.Header, len(r.header)),
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+
+	for k, v := range r.header {
+		if k != "Content-Length" {
+			resp.Headers[k] = append([]string(nil), v...)
+		}
+	}
+	if len(resp.Headers) == 0 {
+		resp.Headers = nil
+	}
+
+	body := bytes.TrimSpace(r.body.Bytes())
+	switch {
+	case len(body) == 0:
+		// no body
+	case strings.Contains(r.header.Get("Content-Type"), "json") && json.Valid(body):
+		resp.Body = append(json.RawMessage(nil), body...)
+	default:
+		resp.Body, _ = json.Marshal(string(body))
+	}
+
+	return resp
+}
@@ -0,0 +1,169 @@
+package hrt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBatchHandler(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		r := NewRouter(DefaultOpts)
+		r.Get("/echo", Wrap(func(ctx context.Context, req echoRequest) (echoResponse, error) {
+			return echoResponse{What: req.What}, nil
+		}))
+		r.Post("/echo", Wrap(func(ctx context.Context, req echoResponse) (echoResponse, error) {
+			who := RequestFromContext(ctx).Header.Get("X-Who")
+			return echoResponse{What: who + ": " + req.What}, nil
+		}))
+		r.Get("/cookies", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Set-Cookie", "a=1")
+			w.Header().Add("Set-Cookie", "b=2")
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		r.Get("/panic", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("oops")
+		}))
+		r.Post("/batch", NewBatchHandler(r, BatchOpts{Concurrency: concurrency}))
+
+		body := `[
+			{"method": "GET", "path": "/echo?what=hi!"},
+			{"method": "GET", "path": "/echo?what=hi"},
+			{"method": "POST", "path": "/echo", "body": {"what": "hello"}},
+			{"method": "POST", "path": "/echo", "headers": {"X-Who": "sub"}, "body": {"what": "hey"}},
+			{"method": "GET", "path": "/missing"},
+			{"method": "POST", "path": "/batch", "body": []},
+			{"method": "GET", "path": "/cookies"},
+			{"method": "GET", "path": "/panic"}
+		]`
+
+		req := httptest.NewRequest("POST", "/batch", strings.NewReader(body))
+		req.Header.Set("X-Who", "parent")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+
+		var resps []BatchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resps); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := []struct {
+			status int
+			body   string
+		}{
+			{200, `{"what":"hi!"}`},
+			{400, `{"error":"400: enthusiasm required"}`},
+			{200, `{"what":"parent: hello"}`},
+			{200, `{"what":"sub: hey"}`},
+			{404, `"404 page not found"`},
+			{400, `{"error":"400: batch requests cannot be nested"}`},
+			{204, ``},
+			{500, `{"error":"500: internal server error"}`},
+		}
+
+		if len(resps) != len(expect) {
+			t.Fatalf("expected %d responses, got %d", len(expect), len(resps))
+		}
+
+		for i, resp := range resps {
+			if resp.Status != expect[i].status || string(resp.Body) != expect[i].body {
+				t.Errorf("concurrency %d: response %d: expected %d %s, got %d %s",
+					concurrency, i, expect[i].status, expect[i].body, resp.Status, resp.Body)
+			}
+		}
+
+		if cookies := resps[6].Headers.Values("Set-Cookie"); !reflect.DeepEqual(cookies, []string{"a=1", "b=2"}) {
+			t.Errorf("concurrency %d: unexpected Set-Cookie headers %q", concurrency, cookies)
+		}
+	}
+}
+
+func TestBatchHandler_parentOnlyHeaders(t *testing.T) {
+	opts := DefaultOpts
+	opts.Compression = &Compression{MinSize: 1}
+	opts.ConditionalGET = true
+
+	r := NewRouter(opts)
+	r.Get("/echo", Wrap(func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{What: req.What}, nil
+	}))
+	r.Post("/batch", NewBatchHandler(r, BatchOpts{}))
+
+	req := httptest.NewRequest("POST", "/batch", strings.NewReader(`[{"method": "GET", "path": "/echo?what=hi!"}]`))
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", "*")
+	req.Header.Set("If-Modified-Since", "Sat, 01 Jan 2050 00:00:00 GMT")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != 200 || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("unexpected status %d with Content-Encoding %q", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+
+	var resps []BatchResponse
+	if err := json.Unmarshal([]byte(decompressTestBody(t, "gzip", rec.Body.Bytes())), &resps); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resps) != 1 || resps[0].Status != 200 || string(resps[0].Body) != `{"what":"hi!"}` {
+		t.Fatalf("unexpected responses %+v", resps)
+	}
+	if enc := resps[0].Headers.Get("Content-Encoding"); enc != "" {
+		t.Errorf("unexpected Content-Encoding %q in sub-response", enc)
+	}
+}
+
+func TestBatchHandler_errorWriter(t *testing.T) {
+	opts := DefaultOpts
+	opts.ErrorWriter = TextErrorWriter
+
+	r := NewRouter(opts)
+	r.Get("/panic", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+	r.Post("/batch", NewBatchHandler(r, BatchOpts{}))
+
+	body := `[{"method": "GET", "path": "/panic"}, {"method": "GET", "path": "/%zz"}]`
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/batch", strings.NewReader(body)))
+
+	var resps []BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resps); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []BatchResponse{
+		{Status: 500, Body: json.RawMessage(`"500: internal server error"`)},
+		{Status: 400, Body: json.RawMessage(`"400: invalid request: parse \"/%zz\": invalid URL escape \"%zz\""`)},
+	}
+	if len(resps) != len(expect) {
+		t.Fatalf("expected %d responses, got %d", len(expect), len(resps))
+	}
+	for i, resp := range resps {
+		if resp.Status != expect[i].Status || string(resp.Body) != string(expect[i].Body) {
+			t.Errorf("response %d: expected %d %s, got %d %s", i, expect[i].Status, expect[i].Body, resp.Status, resp.Body)
+		}
+	}
+}
+
+func TestBatchHandler_maxRequests(t *testing.T) {
+	h := NewBatchHandler(noopHandler, BatchOpts{MaxRequests: 1})
+
+	body := `[{"method": "GET", "path": "/"}, {"method": "GET", "path": "/"}]`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/batch", strings.NewReader(body)))
+
+	if rec.Code != 413 {
+		t.Errorf("expected status 413, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package hrt
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// DefaultBufferSize is the default maximum number of bytes of an encoded
+// response that are buffered before it is streamed to the client.
+const DefaultBufferSize = 64 * 1024
+
+// maxPooledBufferSize is the maximum capacity of a buffer that is returned to
+// the pool. Larger buffers are left for the garbage collector.
+const maxPooledBufferSize = 1024 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// responseBuffer is an http.ResponseWriter that buffers the response body so
+// that it can be inspected or discarded before being written. Once more than
+// limit bytes are written, the buffer is flushed and the rest of the body is
+// streamed directly. Headers are written directly into the underlying
+// ResponseWriter's header map.
+type responseBuffer struct {
+	w         http.ResponseWriter
+	out       io.Writer // w or a compressor writing into w
+	buf       *bytes.Buffer
+	status    int
+	limit     int // negative means unlimited
+	streaming bool
+
+	compression *Compression
+	encoding    string
+	compressor  io.WriteCloser
+}
+
+var _ http.ResponseWriter = (*responseBuffer)(nil)
+
+// newResponseBuffer creates a new responseBuffer. A negative limit buffers the
+// whole response. The buffer must be released using Release.
+func newResponseBuffer(w http.ResponseWriter, limit int) *responseBuffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return &responseBuffer{w: w, out: w, buf: buf, limit: limit}
+}
+
+// Compress makes the buffer compress the response if the client accepts a
+// supported content coding and the response is large enough. c may be nil, in
+// which case nothing is done.
+func (b *responseBuffer) Compress(r *http.Request, c *Compression) {
+	if c == nil {
+		return
+	}
+	addVary(b.w.Header(), "Accept-Encoding")
+	b.compression = c
+	b.encoding = negotiateEncoding(r)
+}
+
+// WillCompress returns true if the response will be compressed once it is
+// flushed. It is only meaningful before the response is streaming.
+func (b *responseBuffer) WillCompress() bool {
+	if b.streaming {
+		return b.compressor != nil
+	}
+	return b.encoding != "" &&
+		b.buf.Len() >= b.compression.MinSize &&
+		b.w.Header().Get("Content-Encoding") == ""
+}
+
+// Encoding returns the negotiated content coding of the response.
+func (b *responseBuffer) Encoding() string {
+	return b.encoding
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.w.Header()
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if !b.streaming && b.limit >= 0 && b.buf.Len()+len(p) > b.limit {
+		if err := b.startStreaming(true); err != nil {
+			return 0, err
+		}
+	}
+	if b.streaming {
+		return b.out.Write(p)
+	}
+	return b.buf.Write(p)
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// Streaming returns true if the buffer has been flushed and the response is
+// being written directly. A streaming response can no longer be discarded.
+func (b *responseBuffer) Streaming() bool {
+	return b.streaming
+}
+
+// Bytes returns the buffered body.
+func (b *responseBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Flush writes the buffered status and body into the underlying
+// ResponseWriter and finishes the response. If the whole body was buffered and
+// is not compressed, Content-Length is set.
+func (b *responseBuffer) Flush() error {
+	if !b.streaming {
+		if !b.WillCompress() && b.w.Header().Get("Content-Length") == "" {
+			b.w.Header().Set("Content-Length", strconv.Itoa(b.buf.Len()))
+		}
+		if err := b.startStreaming(false); err != nil {
+			return err
+		}
+	}
+	if b.compressor != nil {
+		return b.compressor.Close()
+	}
+	return nil
+}
+
+// startStreaming writes the buffered status and body and switches to writing
+// directly. overflow is true if the body exceeded the buffer limit, in which
+// case it is always large enough to be compressed.
+func (b *responseBuffer) startStreaming(overflow bool) error {
+	compress := b.WillCompress() ||
+		(overflow && b.encoding != "" && b.w.Header().Get("Content-Encoding") == "")
+
+	if compress {
+		compressor, err := newCompressor(b.w, b.encoding, b.compression.level())
+		if err != nil {
+			return err
+		}
+
+		h := b.w.Header()
+		h.Set("Content-Encoding", b.encoding)
+		h.Del("Content-Length")
+
+		b.compressor = compressor
+		b.out = compressor
+	}
+
+	b.streaming = true
+	if b.status != 0 {
+		b.w.WriteHeader(b.status)
+	}
+	_, err := b.out.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+// Release returns the buffer to the pool. The responseBuffer must not be used
+// afterwards.
+func (b *responseBuffer) Release() {
+	if b.buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(b.buf)
+	}
+	b.buf = nil
+}
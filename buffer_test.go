@@ -0,0 +1,86 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type failingResponse struct {
+	Size int
+}
+
+// partialEncoder writes a partial response of the given size before failing.
+type partialEncoder struct{ Encoder }
+
+func (e partialEncoder) Encode(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{" + strings.Repeat(" ", v.(failingResponse).Size)))
+	return errors.New("oops")
+}
+
+func TestBufferedResponse(t *testing.T) {
+	opts := DefaultOpts
+	opts.Encoder = partialEncoder{JSONEncoder}
+	opts.BufferSize = 16
+
+	tests := []struct {
+		name   string
+		size   int
+		status int
+		body   string
+	}{
+		{
+			name:   "buffered",
+			size:   4,
+			status: 500,
+			body:   `{"error":"500: oops"}`,
+		},
+		{
+			name:   "streamed",
+			size:   32,
+			status: 200,
+			body:   "{",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewRouter(opts)
+			r.Get("/", Wrap(func(ctx context.Context, req None) (failingResponse, error) {
+				return failingResponse{Size: test.size}, nil
+			}))
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+		})
+	}
+}
+
+func TestBufferedResponse_contentLength(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	r.Get("/", Wrap(func(ctx context.Context, req None) (echoResponse, error) {
+		return echoResponse{What: "hi"}, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if l := rec.Header().Get("Content-Length"); l != "14" {
+		t.Errorf("expected Content-Length 14, got %q", l)
+	}
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type generator struct {
+	pkg       *pkgInfo
+	generated map[string]bool
+	body      bytes.Buffer
+	imports   map[string]bool
+}
+
+func newGenerator(pkg *pkgInfo, typeNames []string) *generator {
+	g := &generator{
+		pkg:       pkg,
+		generated: make(map[string]bool),
+		imports:   map[string]bool{hrtPath: true},
+	}
+	for _, name := range typeNames {
+		g.generated[name] = true
+	}
+	return g
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// source returns the formatted source of the generated file.
+func (g *generator) source() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by hrtgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg.name)
+	fmt.Fprintf(&buf, "import (\n")
+	if g.imports["strconv"] {
+		fmt.Fprintf(&buf, "\t\"strconv\"\n\n")
+	}
+	fmt.Fprintf(&buf, "\thrt %q\n", hrtPath)
+	fmt.Fprintf(&buf, ")\n")
+	buf.Write(g.body.Bytes())
+	return formatSource(&buf)
+}
+
+// field is an exported field of a struct type.
+type field struct {
+	name string
+	tag  reflect.StructTag
+	typ  *typ
+}
+
+func (g *generator) fields(name string) ([]field, error) {
+	decl, ok := g.pkg.types[name]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+
+	st, ok := decl.spec.Type.(*ast.StructType)
+	if !ok || decl.spec.Assign.IsValid() {
+		return nil, fmt.Errorf("not a struct type")
+	}
+	if decl.spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic types are not supported")
+	}
+
+	methods := g.pkg.methods[name]
+	for _, m := range []string{"MarshalJSON", "MarshalText", "DecodeURL", "AppendJSON", "CheckEnums"} {
+		if _, ok := methods[m]; ok {
+			return nil, fmt.Errorf("type already has a %s method", m)
+		}
+	}
+
+	var fields []field
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("embedded fields are not supported")
+		}
+
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(s)
+		}
+
+		t := g.pkg.resolve(f.Type, decl.imports)
+		for _, n := range f.Names {
+			if n.IsExported() {
+				fields = append(fields, field{n.Name, tag, t})
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func (g *generator) generateType(name string) error {
+	fields, err := g.fields(name)
+	if err != nil {
+		return err
+	}
+
+	if err := g.generateDecodeURL(name, fields); err != nil {
+		return err
+	}
+	if err := g.generateAppendJSON(name, fields); err != nil {
+		return err
+	}
+	g.generateCheckEnums(name, fields)
+	return nil
+}
+
+// generateDecodeURL generates the equivalent of hrt.URLDecoder.
+func (g *generator) generateDecodeURL(name string, fields []field) error {
+	var body bytes.Buffer
+	printf := func(format string, args ...any) {
+		fmt.Fprintf(&body, format, args...)
+	}
+
+	for _, f := range fields {
+		if f.typ.special == "hrt.IfMatch" {
+			continue // populated from headers
+		}
+
+		source, key, opts := fieldTag(f)
+		if source == "json" && key == "-" {
+			continue // explicitly ignored
+		}
+
+		policy, err := policyOptions(opts)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+
+		if source == "json" {
+			printf("if err := d.JSON(%q, &v.%s, p); err != nil {\n", key, f.name)
+			printf("return err\n")
+			printf("}\n")
+			continue
+		}
+
+		printf("{\n")
+		if len(policy) > 0 {
+			printf("p := p\n")
+			for _, line := range policy {
+				printf("%s\n", line)
+			}
+		}
+
+		if f.typ.kind == kindSlice {
+			// Slices may take all values of their key, which
+			// hrt.ParseURLValues decides using reflection.
+			switch source {
+			case "form":
+				printf("vals, ok := d.FormValues(%q)\n", key)
+			case "url":
+				printf("val, ok := d.Param(%q)\n", key)
+				printf("vals := []string{val}\n")
+			case "name":
+				printf("vals, ok := d.NamedValues(%q)\n", key)
+			}
+			printf("if err := hrt.ParseURLValues(d, &v.%s, vals, ok, p); err != nil {\n", f.name)
+		} else {
+			switch source {
+			case "form":
+				printf("val, ok := d.Form(%q)\n", key)
+			case "url":
+				printf("val, ok := d.Param(%q)\n", key)
+			case "name":
+				printf("val, ok := d.Named(%q)\n", key)
+			}
+			printf("if err := %s(d, &v.%s, val, ok, p); err != nil {\n", parseFunc(f.typ), f.name)
+		}
+		printf("return err\n")
+		printf("}\n")
+		printf("}\n")
+	}
+
+	g.printf("\n// DecodeURL implements hrt.URLDecodable.\n")
+	g.printf("func (v *%s) DecodeURL(d *hrt.URLDecodeState) error {\n", name)
+	if body.Len() > 0 {
+		g.printf("p := d.Policy()\n")
+		g.body.Write(body.Bytes())
+	}
+	g.printf("return nil\n")
+	g.printf("}\n")
+	return nil
+}
+
+// fieldTag mirrors hrt's fieldTag.
+func fieldTag(f field) (source, key, opts string) {
+	for _, tag := range []string{"form", "query", "schema"} {
+		if name, opts := parseTag(f.tag.Get(tag)); name != "" {
+			return "form", name, opts
+		}
+	}
+	if name, opts := parseTag(f.tag.Get("url")); name != "" {
+		return "url", name, opts
+	}
+	if name, _ := parseTag(f.tag.Get("json")); name != "" {
+		return "json", name, ""
+	}
+	return "name", f.name, ""
+}
+
+func parseTag(tag string) (name, opts string) {
+	name, opts, _ = strings.Cut(tag, ",")
+	return name, opts
+}
+
+// policyOptions returns the statements that apply the tag options to the
+// policy p. It mirrors hrt's ParsePolicy.withTagOptions.
+func policyOptions(opts string) ([]string, error) {
+	layouts := map[string]string{
+		"rfc3339":   strconv.Quote(time.RFC3339),
+		"date":      strconv.Quote("2006-01-02"),
+		"unix":      "hrt.TimeLayoutUnix",
+		"unixmilli": "hrt.TimeLayoutUnixMilli",
+	}
+
+	var lines []string
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+
+		k, v, ok := strings.Cut(opt, "=")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case k == "bool" && v == "strict":
+			lines = append(lines, "p.Bool = hrt.BoolStrict")
+		case k == "bool" && v == "presence":
+			lines = append(lines, "p.Bool = hrt.BoolPresence")
+		case k == "empty" && v == "keep":
+			lines = append(lines, "p.Empty = hrt.EmptyKeep")
+		case k == "empty" && v == "zero":
+			lines = append(lines, "p.Empty = hrt.EmptyZero")
+		case k == "int" && v == "base10":
+			lines = append(lines, "p.IntPrefixes = false")
+		case k == "int" && v == "prefixed":
+			lines = append(lines, "p.IntPrefixes = true")
+		case k == "layout" && layouts[v] != "":
+			lines = append(lines, "p.TimeLayout = "+layouts[v])
+		default:
+			return nil, fmt.Errorf("unknown tag option %q", opt)
+		}
+	}
+	return lines, nil
+}
+
+// parseFunc returns the function that parses values of the type.
+func parseFunc(t *typ) string {
+	if t.textUnmarshaler || t.special != "" {
+		return "hrt.ParseURLValue"
+	}
+	switch t.kind {
+	case kindString:
+		return "hrt.ParseURLString"
+	case kindBool:
+		return "hrt.ParseURLBool"
+	case kindInt:
+		return "hrt.ParseURLInt"
+	case kindUint:
+		return "hrt.ParseURLUint"
+	case kindFloat:
+		return "hrt.ParseURLFloat"
+	default:
+		return "hrt.ParseURLValue"
+	}
+}
+
+// generateAppendJSON generates the equivalent of json.Marshal.
+func (g *generator) generateAppendJSON(name string, fields []field) error {
+	type jsonField struct {
+		field
+		key       string
+		omitEmpty bool
+	}
+
+	var jsonFields []jsonField
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		tag := f.tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		key, opts := parseTag(tag)
+		if !isValidTag(key) {
+			key = f.name
+		}
+		if seen[key] {
+			return fmt.Errorf("field %s: duplicate JSON field %q", f.name, key)
+		}
+		seen[key] = true
+
+		var omitEmpty bool
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "string", "omitzero":
+				return fmt.Errorf("field %s: json option %q is not supported", f.name, opt)
+			}
+		}
+
+		if omitEmpty && emptyCheck(f.typ, "x") == "" && f.typ.kind != kindStruct && f.typ.special != "time.Time" {
+			return fmt.Errorf("field %s: omitempty is not supported for this type", f.name)
+		}
+
+		jsonFields = append(jsonFields, jsonField{f, key, omitEmpty})
+	}
+
+	var body bytes.Buffer
+	a := &jsonAppender{g: g, body: &body}
+
+	for i, f := range jsonFields {
+		value := "v." + f.name
+
+		cond := ""
+		if f.omitEmpty {
+			cond = emptyCheck(f.typ, value)
+		}
+		if cond != "" {
+			a.printf("if %s {\n", cond)
+		}
+
+		if i > 0 {
+			a.printf("if len(b) > n {\n")
+			a.printf("b = append(b, ',')\n")
+			a.printf("}\n")
+		}
+
+		key, _ := json.Marshal(f.key)
+		a.printf("b = append(b, %q...)\n", string(key)+":")
+
+		// A non-empty pointer or slice is never nil.
+		nonNil := cond != ""
+		if err := a.appendValue(f.typ, value, 0, nonNil); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+
+		if cond != "" {
+			a.printf("}\n")
+		}
+	}
+
+	g.printf("\n// AppendJSON implements hrt.JSONAppender.\n")
+	g.printf("func (v %s) AppendJSON(b []byte) ([]byte, error) {\n", name)
+	if a.usesErr {
+		g.printf("var err error\n")
+	}
+	g.printf("b = append(b, '{')\n")
+	if len(jsonFields) > 1 {
+		g.printf("n := len(b)\n")
+	}
+	g.body.Write(body.Bytes())
+	g.printf("b = append(b, '}')\n")
+	g.printf("return b, nil\n")
+	g.printf("}\n")
+	return nil
+}
+
+// jsonAppender generates the body of an AppendJSON method.
+type jsonAppender struct {
+	g       *generator
+	body    *bytes.Buffer
+	usesErr bool
+}
+
+func (a *jsonAppender) printf(format string, args ...any) {
+	fmt.Fprintf(a.body, format, args...)
+}
+
+// needsFallback returns true if values of the type are encoded using
+// encoding/json. Pointers, slices and arrays of such types are encoded using
+// encoding/json as a whole, since it calls methods with pointer receivers on
+// their elements.
+func (a *jsonAppender) needsFallback(t *typ) bool {
+	if t.jsonMarshaler || t.special == "time.Time" {
+		return true
+	}
+	switch t.kind {
+	case kindString, kindBool, kindInt, kindUint, kindFloat:
+		return false
+	case kindStruct:
+		return !a.g.generated[t.name]
+	case kindSlice:
+		// Byte slices are encoded as base64.
+		return (t.elem.kind == kindUint && t.elem.bits == 8) || a.needsFallback(t.elem)
+	case kindPtr, kindArray:
+		return a.needsFallback(t.elem)
+	default:
+		return true
+	}
+}
+
+// appendValue generates code that appends the JSON encoding of the value of
+// type t to b. depth is used to name loop variables. If nonNil is true, the
+// value is known not to be a nil pointer or slice.
+func (a *jsonAppender) appendValue(t *typ, value string, depth int, nonNil bool) error {
+	if t.jsonMarshalerPtr {
+		return fmt.Errorf("types with pointer receiver MarshalJSON or MarshalText methods are not supported")
+	}
+
+	if a.needsFallback(t) {
+		a.usesErr = true
+		a.printf("if b, err = hrt.AppendJSONValue(b, %s); err != nil {\n", value)
+		a.printf("return b, err\n")
+		a.printf("}\n")
+		return nil
+	}
+
+	switch t.kind {
+	case kindString:
+		a.printf("b = hrt.AppendJSONString(b, string(%s))\n", value)
+	case kindBool:
+		a.g.imports["strconv"] = true
+		a.printf("b = strconv.AppendBool(b, bool(%s))\n", value)
+	case kindInt:
+		a.g.imports["strconv"] = true
+		a.printf("b = strconv.AppendInt(b, int64(%s), 10)\n", value)
+	case kindUint:
+		a.g.imports["strconv"] = true
+		a.printf("b = strconv.AppendUint(b, uint64(%s), 10)\n", value)
+	case kindFloat:
+		a.usesErr = true
+		a.printf("if b, err = hrt.AppendJSONFloat(b, float64(%s), %d); err != nil {\n", value, t.bits)
+		a.printf("return b, err\n")
+		a.printf("}\n")
+
+	case kindStruct:
+		a.usesErr = true
+		a.printf("if b, err = %s.AppendJSON(b); err != nil {\n", value)
+		a.printf("return b, err\n")
+		a.printf("}\n")
+
+	case kindPtr:
+		elem := "*" + value
+		if t.elem.kind == kindStruct {
+			// Methods are called through the pointer.
+			elem = value
+		}
+		if !nonNil {
+			a.printf("if %s == nil {\n", value)
+			a.printf("b = append(b, \"null\"...)\n")
+			a.printf("} else {\n")
+		}
+		if err := a.appendValue(t.elem, elem, depth, false); err != nil {
+			return err
+		}
+		if !nonNil {
+			a.printf("}\n")
+		}
+
+	case kindSlice, kindArray:
+		elem := fmt.Sprintf("e%d", depth)
+		checkNil := t.kind == kindSlice && !nonNil
+		if checkNil {
+			a.printf("if %s == nil {\n", value)
+			a.printf("b = append(b, \"null\"...)\n")
+			a.printf("} else {\n")
+		}
+		a.printf("b = append(b, '[')\n")
+		a.printf("for i, %s := range %s {\n", elem, value)
+		a.printf("if i > 0 {\n")
+		a.printf("b = append(b, ',')\n")
+		a.printf("}\n")
+		if err := a.appendValue(t.elem, elem, depth+1, false); err != nil {
+			return err
+		}
+		a.printf("}\n")
+		a.printf("b = append(b, ']')\n")
+		if checkNil {
+			a.printf("}\n")
+		}
+	}
+
+	return nil
+}
+
+// emptyCheck returns the condition under which the value is not empty as
+// defined by encoding/json's omitempty, or an empty string if the type is
+// never empty or unknown.
+func emptyCheck(t *typ, value string) string {
+	if t.special == "time.Time" {
+		return ""
+	}
+	switch t.kind {
+	case kindString:
+		return value + ` != ""`
+	case kindBool:
+		return value
+	case kindInt, kindUint, kindFloat:
+		return value + " != 0"
+	case kindPtr, kindInterface:
+		return value + " != nil"
+	case kindSlice, kindArray, kindMap:
+		return "len(" + value + ") != 0"
+	default:
+		return ""
+	}
+}
+
+// isValidTag mirrors encoding/json's isValidTag.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// generateCheckEnums generates the equivalent of the reflection-based enum
+// checks of the decoders.
+func (g *generator) generateCheckEnums(name string, fields []field) {
+	g.printf("\n// CheckEnums implements hrt.EnumChecker.\n")
+	g.printf("func (v %s) CheckEnums() error {\n", name)
+
+	for _, f := range fields {
+		if f.typ.noEnums() {
+			continue
+		}
+
+		fieldName := fieldTagName(f)
+		value := "v." + f.name
+
+		switch {
+		case f.typ.enum && f.typ.isBasic():
+			g.printf("if err := hrt.CheckEnum(%q, %s); err != nil {\n", fieldName, value)
+			g.printf("return err\n")
+			g.printf("}\n")
+
+		case f.typ.kind == kindPtr && f.typ.elem.enum && f.typ.elem.isBasic():
+			g.printf("if %s != nil {\n", value)
+			g.printf("if err := hrt.CheckEnum(%q, *%s); err != nil {\n", fieldName, value)
+			g.printf("return err\n")
+			g.printf("}\n")
+			g.printf("}\n")
+
+		default:
+			g.printf("if err := hrt.CheckEnumsOf(%q, %s); err != nil {\n", fieldName, value)
+			g.printf("return err\n")
+			g.printf("}\n")
+		}
+	}
+
+	g.printf("return nil\n")
+	g.printf("}\n")
+}
+
+// fieldTagName mirrors hrt's fieldTagName.
+func fieldTagName(f field) string {
+	for _, tag := range []string{"json", "query", "form", "schema", "url"} {
+		name, _ := parseTag(f.tag.Get(tag))
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.name
+}
@@ -0,0 +1,214 @@
+// Command hrtgen generates decoders and encoders for request and response
+// types that avoid reflection where possible. It is meant to be used with go
+// generate:
+//
+//	//go:generate go run libdb.so/hrt/v2/cmd/hrtgen -type ListRequest,Item
+//
+// For each given struct type, hrtgen generates the following methods, which
+// hrt prefers over reflection:
+//
+//   - DecodeURL, implementing hrt.URLDecodable, used by hrt.URLDecoder.
+//   - AppendJSON, implementing hrt.JSONAppender, used by hrt.JSONEncoder.
+//   - CheckEnums, implementing hrt.EnumChecker, used by all decoders.
+//
+// The generated methods behave exactly like the reflection-based code. Fields
+// whose types hrtgen cannot handle statically, such as types from other
+// packages, fall back to reflection or encoding/json for that field only.
+// Slices decoded from repeated URL values are parsed using reflection as well.
+//
+// Only decoding and encoding are generated. Before DecodeURL is called, hrt
+// still sets the values of `default` tags and the hrt.IfMatch fields of a
+// request using reflection.
+//
+// hrtgen only looks at the syntax of the package, so it does not require the
+// package to compile. Types with embedded fields are not supported.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const hrtPath = "libdb.so/hrt/v2"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("hrtgen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of type names; required")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_hrt.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hrtgen -type T[,T...] [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	types := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(types[0])+"_hrt.go")
+	}
+
+	src, err := generate(dir, filepath.Base(*output), types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate generates the source of the output file for the given types of the
+// package in dir. The output file itself is ignored when loading the package.
+func generate(dir, output string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGenerator(pkg, typeNames)
+	for _, name := range typeNames {
+		if err := g.generateType(name); err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+	}
+
+	return g.source()
+}
+
+// pkgInfo is the syntax of a package, which is all that hrtgen needs.
+type pkgInfo struct {
+	name  string
+	types map[string]*typeDecl
+	// methods maps type names to their method names and whether they have a
+	// pointer receiver.
+	methods map[string]map[string]bool
+}
+
+type typeDecl struct {
+	spec    *ast.TypeSpec
+	imports map[string]string // local name -> import path
+}
+
+func loadPackage(dir, output string) (*pkgInfo, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &pkgInfo{
+		name:    bp.Name,
+		types:   make(map[string]*typeDecl),
+		methods: make(map[string]map[string]bool),
+	}
+
+	fset := token.NewFileSet()
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		imports := fileImports(f)
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					pkg.types[spec.Name.Name] = &typeDecl{spec, imports}
+				}
+
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) != 1 {
+					continue
+				}
+				recv, ptr := receiverName(decl.Recv.List[0].Type)
+				if recv == "" {
+					continue
+				}
+				if pkg.methods[recv] == nil {
+					pkg.methods[recv] = make(map[string]bool)
+				}
+				pkg.methods[recv][decl.Name.Name] = ptr
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if path == hrtPath {
+			name = "hrt"
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+func receiverName(expr ast.Expr) (name string, ptr bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+		ptr = true
+	}
+	switch expr := expr.(type) {
+	case *ast.IndexExpr: // generic receiver
+		expr2, _ := expr.X.(*ast.Ident)
+		if expr2 != nil {
+			return expr2.Name, ptr
+		}
+	case *ast.Ident:
+		return expr.Name, ptr
+	}
+	return "", false
+}
+
+// formatSource formats the generated source, reporting the source along with
+// the error if it does not parse, which would be a bug in hrtgen.
+func formatSource(buf *bytes.Buffer) ([]byte, error) {
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
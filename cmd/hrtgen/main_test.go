@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	const dir = "../../internal/hrtgentest"
+
+	got, err := generate(dir, "types_hrt.go", []string{"ListRequest", "Item", "Owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect, err := os.ReadFile(filepath.Join(dir, "types_hrt.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != string(expect) {
+		t.Errorf("generated code is out of date; run go generate ./...\n got:\n%s", got)
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		expect string
+	}{
+		{
+			name:   "not found",
+			src:    ``,
+			expect: `type T: not found`,
+		},
+		{
+			name:   "not a struct",
+			src:    `type T string`,
+			expect: `type T: not a struct type`,
+		},
+		{
+			name:   "embedded",
+			src:    `type E struct{}; type T struct { E }`,
+			expect: `type T: embedded fields are not supported`,
+		},
+		{
+			name:   "marshaler",
+			src:    `type T struct{}; func (T) MarshalJSON() ([]byte, error) { return nil, nil }`,
+			expect: `type T: type already has a MarshalJSON method`,
+		},
+		{
+			name:   "unknown tag option",
+			src:    "type T struct { A int `query:\"a,int=hex\"` }",
+			expect: `type T: field A: unknown tag option "int=hex"`,
+		},
+		{
+			name:   "string option",
+			src:    "type T struct { A int `json:\"a,string\"` }",
+			expect: `type T: field A: json option "string" is not supported`,
+		},
+		{
+			name:   "duplicate",
+			src:    "type T struct { A int `json:\"a\"`; B int `json:\"a\"` }",
+			expect: `type T: field B: duplicate JSON field "a"`,
+		},
+		{
+			name:   "omitempty on unknown type",
+			src:    "import \"net/netip\"; type T struct { A netip.Addr `json:\"a,omitempty\"` }",
+			expect: `type T: field A: omitempty is not supported for this type`,
+		},
+		{
+			name:   "pointer marshaler",
+			src:    `type M int; func (*M) MarshalText() ([]byte, error) { return nil, nil }; type T struct { A M }`,
+			expect: `type T: field A: types with pointer receiver MarshalJSON or MarshalText methods are not supported`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package p\n" + strings.ReplaceAll(test.src, "; ", "\n")
+			if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := generate(dir, "t_hrt.go", []string{"T"})
+			if err == nil || err.Error() != test.expect {
+				t.Errorf("unexpected error:\n expected: %s\n got: %v", test.expect, err)
+			}
+		})
+	}
+}
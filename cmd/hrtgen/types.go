@@ -0,0 +1,197 @@
+package main
+
+import (
+	"go/ast"
+)
+
+// kind is the kind of a type as far as hrtgen can tell from the syntax.
+type kind uint8
+
+const (
+	kindOther kind = iota // unknown, handled using reflection
+	kindString
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindStruct // a struct type declared in the package
+	kindPtr
+	kindSlice
+	kindArray
+	kindMap
+	kindInterface
+)
+
+// typ describes a field type.
+type typ struct {
+	kind kind
+	// name is the name of the type if it is declared in the package.
+	name string
+	// bits is the size of numeric types.
+	bits int
+	// elem is the element type of pointers, slices and arrays.
+	elem *typ
+	// special is the name of a type from another package that is known to
+	// hrtgen, e.g. "time.Time".
+	special string
+
+	// jsonMarshaler is true if the type has a MarshalJSON or MarshalText
+	// method, and jsonMarshalerPtr is true if that method has a pointer
+	// receiver.
+	jsonMarshaler    bool
+	jsonMarshalerPtr bool
+	// textUnmarshaler is true if the type has an UnmarshalText method.
+	textUnmarshaler bool
+	// enum is true if the type has an Enum method with a value receiver.
+	enum bool
+}
+
+var basicTypes = map[string]typ{
+	"string":  {kind: kindString},
+	"bool":    {kind: kindBool},
+	"int":     {kind: kindInt, bits: 64},
+	"int8":    {kind: kindInt, bits: 8},
+	"int16":   {kind: kindInt, bits: 16},
+	"int32":   {kind: kindInt, bits: 32},
+	"rune":    {kind: kindInt, bits: 32},
+	"int64":   {kind: kindInt, bits: 64},
+	"uint":    {kind: kindUint, bits: 64},
+	"uint8":   {kind: kindUint, bits: 8},
+	"byte":    {kind: kindUint, bits: 8},
+	"uint16":  {kind: kindUint, bits: 16},
+	"uint32":  {kind: kindUint, bits: 32},
+	"uint64":  {kind: kindUint, bits: 64},
+	"float32": {kind: kindFloat, bits: 32},
+	"float64": {kind: kindFloat, bits: 64},
+	"any":     {kind: kindInterface},
+}
+
+// resolve resolves the type expression expr, which appears in a file with the
+// given imports.
+func (pkg *pkgInfo) resolve(expr ast.Expr, imports map[string]string) *typ {
+	return pkg.resolveVisited(expr, imports, map[string]bool{})
+}
+
+func (pkg *pkgInfo) resolveVisited(expr ast.Expr, imports map[string]string, visited map[string]bool) *typ {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return pkg.resolveVisited(expr.X, imports, visited)
+
+	case *ast.Ident:
+		if _, ok := pkg.types[expr.Name]; ok {
+			return pkg.resolveNamed(expr.Name, visited)
+		}
+		if t, ok := basicTypes[expr.Name]; ok {
+			return &t
+		}
+		return &typ{kind: kindOther}
+
+	case *ast.SelectorExpr:
+		x, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return &typ{kind: kindOther}
+		}
+		switch path := imports[x.Name]; {
+		case path == "time" && expr.Sel.Name == "Time":
+			return &typ{kind: kindOther, special: "time.Time", jsonMarshaler: true, textUnmarshaler: true}
+		case path == "time" && expr.Sel.Name == "Duration":
+			return &typ{kind: kindInt, bits: 64, special: "time.Duration"}
+		case path == hrtPath && expr.Sel.Name == "IfMatch":
+			return &typ{kind: kindSlice, elem: &typ{kind: kindString}, special: "hrt.IfMatch"}
+		}
+		return &typ{kind: kindOther}
+
+	case *ast.StarExpr:
+		return &typ{kind: kindPtr, elem: pkg.resolveVisited(expr.X, imports, visited)}
+
+	case *ast.ArrayType:
+		k := kindArray
+		if expr.Len == nil {
+			k = kindSlice
+		}
+		return &typ{kind: k, elem: pkg.resolveVisited(expr.Elt, imports, visited)}
+
+	case *ast.MapType:
+		return &typ{kind: kindMap}
+
+	case *ast.InterfaceType:
+		return &typ{kind: kindInterface}
+
+	default:
+		return &typ{kind: kindOther}
+	}
+}
+
+// resolveNamed resolves a type declared in the package. The kind is that of
+// its underlying type, while the methods are its own.
+func (pkg *pkgInfo) resolveNamed(name string, visited map[string]bool) *typ {
+	decl := pkg.types[name]
+	if visited[name] || decl.spec.TypeParams != nil {
+		return &typ{kind: kindOther}
+	}
+	visited[name] = true
+	defer delete(visited, name)
+
+	if decl.spec.Assign.IsValid() {
+		// Aliases are the same type as what they refer to.
+		return pkg.resolveVisited(decl.spec.Type, decl.imports, visited)
+	}
+
+	var t typ
+	if _, ok := decl.spec.Type.(*ast.StructType); ok {
+		t = typ{kind: kindStruct}
+	} else {
+		t = *pkg.resolveVisited(decl.spec.Type, decl.imports, visited)
+		if t.kind == kindOther || t.kind == kindStruct {
+			// The underlying type is an opaque or struct type, e.g.
+			// `type T time.Time`, whose fields we know nothing about.
+			t = typ{kind: kindOther}
+		}
+	}
+
+	t.name = name
+	t.special = ""
+	t.jsonMarshaler, t.jsonMarshalerPtr = false, false
+	t.textUnmarshaler, t.enum = false, false
+
+	methods := pkg.methods[name]
+	for _, m := range []string{"MarshalJSON", "MarshalText"} {
+		if ptr, ok := methods[m]; ok {
+			t.jsonMarshaler = true
+			t.jsonMarshalerPtr = t.jsonMarshalerPtr || ptr
+		}
+	}
+	_, t.textUnmarshaler = methods["UnmarshalText"]
+	if ptr, ok := methods["Enum"]; ok && !ptr {
+		t.enum = true
+	}
+
+	return &t
+}
+
+// isBasic returns true if values of the type are strings, bools or numbers.
+func (t *typ) isBasic() bool {
+	switch t.kind {
+	case kindString, kindBool, kindInt, kindUint, kindFloat:
+		return true
+	default:
+		return false
+	}
+}
+
+// noEnums returns true if values of the type cannot contain an Enum.
+func (t *typ) noEnums() bool {
+	if t.enum {
+		return false
+	}
+	switch {
+	case t.special != "":
+		return true
+	case t.isBasic():
+		return true
+	case t.kind == kindPtr, t.kind == kindSlice, t.kind == kindArray:
+		return t.elem.noEnums()
+	default:
+		return false
+	}
+}
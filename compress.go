@@ -0,0 +1,210 @@
+package hrt
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Compression contains options for compressing responses and decompressing
+// requests. Compression is only applied to responses written by Handler.
+type Compression struct {
+	// MinSize is the minimum size in bytes of an encoded response for it to be
+	// compressed. Smaller responses are sent as-is, since compressing them
+	// tends to not be worth it.
+	MinSize int
+	// Level is the compression level, as defined in compress/flate. If zero,
+	// flate.DefaultCompression is used.
+	Level int
+	// MaxRequestSize is the maximum size in bytes of a decompressed request
+	// body. Reading more than that fails with a 413 error, which guards
+	// against small bodies that decompress into huge ones. If zero,
+	// DefaultMaxRequestSize is used; if negative, there is no limit.
+	MaxRequestSize int64
+}
+
+// DefaultMaxRequestSize is the default Compression.MaxRequestSize.
+const DefaultMaxRequestSize = 10 << 20 // 10 MiB
+
+// DefaultCompression is a reasonable default for Opts.Compression.
+var DefaultCompression = &Compression{
+	MinSize: 1024,
+	Level:   flate.DefaultCompression,
+}
+
+func (c *Compression) level() int {
+	if c.Level == 0 {
+		return flate.DefaultCompression
+	}
+	return c.Level
+}
+
+func (c *Compression) maxRequestSize() int64 {
+	if c.MaxRequestSize == 0 {
+		return DefaultMaxRequestSize
+	}
+	return c.MaxRequestSize
+}
+
+// negotiateEncoding returns the content coding to use for a response to r,
+// or an empty string if the response should not be compressed. gzip is
+// preferred over deflate if the client accepts both equally. As specified by
+// RFC 9110, Section 12.5.3, a "*" only applies to codings that are not listed
+// explicitly, so "gzip;q=0, *" never selects gzip.
+func negotiateEncoding(r *http.Request) string {
+	qs := make(map[string]float64, 2)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+
+		switch coding {
+		case "*":
+			wildcard = q
+		case "gzip", "deflate":
+			qs[coding] = q
+		}
+	}
+
+	var best string
+	var bestQ float64
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, ok := qs[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best = coding
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// addVary adds token to the Vary header unless it is already listed.
+func addVary(h http.Header, token string) {
+	for _, v := range h.Values("Vary") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t == "*" || strings.EqualFold(t, token) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", token)
+}
+
+func newCompressor(w io.Writer, encoding string, level int) (io.WriteCloser, error) {
+	switch encoding {
+	case "gzip":
+		return gzip.NewWriterLevel(w, level)
+	case "deflate":
+		return zlib.NewWriterLevel(w, level)
+	default:
+		return nil, errors.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// encodedETag returns the entity tag of a representation compressed with the
+// given content coding, which must differ from the uncompressed one.
+func encodedETag(etag, encoding string) string {
+	if etag == "" || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// stripEncodedETag reverses encodedETag.
+func stripEncodedETag(etag string) string {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if strings.HasSuffix(etag, "-"+encoding+`"`) {
+			return strings.TrimSuffix(etag, "-"+encoding+`"`) + `"`
+		}
+	}
+	return etag
+}
+
+// decompressRequest returns a request whose body is transparently
+// decompressed according to its Content-Encoding and limited to the
+// MaxRequestSize of c.
+func decompressRequest(r *http.Request, c *Compression) (*http.Request, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || r.Body == nil {
+		return r, nil
+	}
+
+	var body io.ReadCloser
+	var err error
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(r.Body)
+	case "deflate":
+		body, err = zlib.NewReader(r.Body)
+	default:
+		return nil, WrapHTTPError(http.StatusUnsupportedMediaType,
+			errors.Errorf("unsupported content encoding %q", encoding))
+	}
+	if err != nil {
+		return nil, WrapHTTPError(http.StatusBadRequest, errors.Wrap(err, "invalid compressed body"))
+	}
+
+	r = r.WithContext(r.Context())
+	r.Header = r.Header.Clone()
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	if max := c.maxRequestSize(); max >= 0 {
+		body = &limitedBody{body, max}
+	}
+	r.Body = decompressedBody{body, r.Body}
+	return r, nil
+}
+
+var errRequestTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+
+// limitedBody fails with errRequestTooLarge once more than n bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		n = int(b.n)
+		b.n = 0
+		return n, errRequestTooLarge
+	}
+	b.n -= int64(n)
+	return n, err
+}
+
+type decompressedBody struct {
+	io.ReadCloser
+	orig io.ReadCloser
+}
+
+func (b decompressedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.orig.Close()
+}
@@ -0,0 +1,196 @@
+package hrt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	opts := DefaultOpts
+	opts.Compression = &Compression{MinSize: 64}
+	opts.BufferSize = 128
+
+	r := NewRouter(opts)
+	r.Get("/echo", Wrap(func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{What: req.What}, nil
+	}))
+	r.Post("/echo", Wrap(func(ctx context.Context, req echoResponse) (echoResponse, error) {
+		return req, nil
+	}))
+
+	small := "hi!"
+	large := strings.Repeat("a", 100) + "!"
+	huge := strings.Repeat("a", 1000) + "!"
+
+	tests := []struct {
+		name           string
+		what           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"small", small, "gzip", ""},
+		{"large gzip", large, "gzip, deflate", "gzip"},
+		{"large deflate", large, "gzip;q=0.5, deflate", "deflate"},
+		{"large identity", large, "", ""},
+		{"large rejected", large, "gzip;q=0", ""},
+		{"huge streamed", huge, "*", "gzip"},
+		{"wildcard after rejection", large, "gzip;q=0, *", "deflate"},
+		{"wildcard rejected", large, "deflate, *;q=0", "deflate"},
+		{"explicit over wildcard", large, "deflate;q=0.5, *;q=0.1", "deflate"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/echo?what="+test.what, nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != 200 {
+				t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("unexpected Vary %q", vary)
+			}
+			if enc := rec.Header().Get("Content-Encoding"); enc != test.encoding {
+				t.Errorf("expected Content-Encoding %q, got %q", test.encoding, enc)
+			}
+
+			body := decompressTestBody(t, test.encoding, rec.Body.Bytes())
+			if expect := `{"what":"` + test.what + `"}`; strings.TrimSpace(body) != expect {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", expect, body)
+			}
+		})
+	}
+
+	t.Run("existing vary", func(t *testing.T) {
+		r := NewRouter(opts)
+		r.With(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Vary", "Origin, accept-encoding")
+				next.ServeHTTP(w, r)
+			})
+		}).Get("/echo", Wrap(func(ctx context.Context, req echoRequest) (echoResponse, error) {
+			return echoResponse{What: req.What}, nil
+		}))
+
+		req := httptest.NewRequest("GET", "/echo?what="+large, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if vary := rec.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Origin, accept-encoding" {
+			t.Errorf("unexpected Vary %q", vary)
+		}
+	})
+
+	t.Run("decompress request", func(t *testing.T) {
+		var body bytes.Buffer
+		gz := gzip.NewWriter(&body)
+		gz.Write([]byte(`{"what":"hello"}`))
+		gz.Close()
+
+		req := httptest.NewRequest("POST", "/echo", &body)
+		req.Header.Set("Content-Encoding", "gzip")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"what":"hello"}` {
+			t.Errorf("unexpected body %s", got)
+		}
+	})
+
+	t.Run("decompressed request too large", func(t *testing.T) {
+		var body bytes.Buffer
+		gz := gzip.NewWriter(&body)
+		gz.Write([]byte(`{"what":"`))
+		gz.Write(bytes.Repeat([]byte("a"), DefaultMaxRequestSize))
+		gz.Write([]byte(`"}`))
+		gz.Close()
+
+		req := httptest.NewRequest("POST", "/echo", &body)
+		req.Header.Set("Content-Encoding", "gzip")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != 413 {
+			t.Errorf("expected status 413, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("unsupported request encoding", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader("{}"))
+		req.Header.Set("Content-Encoding", "br")
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != 415 {
+			t.Errorf("expected status 415, got %d", rec.Code)
+		}
+	})
+}
+
+func TestCompression_conditionalGET(t *testing.T) {
+	opts := DefaultOpts
+	opts.Compression = &Compression{}
+	opts.ConditionalGET = true
+
+	r := NewRouter(opts)
+	r.Get("/", Wrap(func(ctx context.Context, req None) (echoResponse, error) {
+		return echoResponse{What: "hi"}, nil
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if !strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("expected gzip ETag, got %q", etag)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != 304 {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+}
+
+func decompressTestBody(t *testing.T, encoding string, b []byte) string {
+	var r io.Reader = bytes.NewReader(b)
+	var err error
+
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(r)
+	case "deflate":
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,153 @@
+package hrt
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagger describes a response type that knows its own entity tag. If a
+// response implements ETagger, then its ETag is used instead of hashing the
+// encoded body, which allows a 304 response to skip encoding entirely.
+type ETagger interface {
+	// ETag returns the entity tag of the value. The value may be quoted; if it
+	// isn't, it is quoted automatically.
+	ETag() string
+}
+
+// LastModifier describes a response type that knows when it was last
+// modified. It is used to set Last-Modified and to honor If-Modified-Since.
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+// writeConditionalResponse writes resp as the response to a GET or HEAD
+// request, honoring If-None-Match and If-Modified-Since.
+func writeConditionalResponse(w http.ResponseWriter, r *http.Request, opts Opts, resp any) {
+	h := w.Header()
+
+	if lm, ok := resp.(LastModifier); ok {
+		if t := lm.LastModified(); !t.IsZero() {
+			h.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if e, ok := resp.(ETagger); ok {
+		if etag := e.ETag(); etag != "" {
+			h.Set("ETag", quoteETag(etag))
+		}
+	}
+
+	if h.Get("ETag") != "" && notModified(r, h) {
+		writeNotModified(w)
+		return
+	}
+
+	buf := newResponseBuffer(w, -1)
+	buf.Compress(r, opts.Compression)
+	defer buf.Release()
+
+	if err := opts.Encoder.Encode(buf, resp); err != nil {
+		h.Del("ETag")
+		h.Del("Last-Modified")
+		opts.ErrorWriter.WriteError(w, WrapHTTPError(http.StatusInternalServerError, err))
+		return
+	}
+
+	if h.Get("ETag") == "" {
+		h.Set("ETag", computeETag(buf.Bytes()))
+	}
+
+	if buf.WillCompress() {
+		// Compressed representations must have a different entity tag.
+		h.Set("ETag", encodedETag(h.Get("ETag"), buf.Encoding()))
+	}
+
+	if notModified(r, h) {
+		writeNotModified(w)
+		return
+	}
+
+	buf.Flush()
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// notModified returns true if the request's preconditions indicate that the
+// client's cached representation is still fresh. As per RFC 7232,
+// If-Modified-Since is ignored if If-None-Match is present.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatches(inm, h.Get("ETag"), true)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	lm := h.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+
+	imsTime, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	lmTime, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+
+	return !lmTime.After(imsTime)
+}
+
+// computeETag computes a strong entity tag from the given body.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// etagListMatches returns true if the given comma-separated list of entity
+// tags matches etag. A list of "*" matches any existing entity. If weak is
+// true, then the weak comparison function is used; otherwise, weak tags never
+// match.
+func etagListMatches(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	list = strings.TrimSpace(list)
+	if list == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			// Representations that only differ in content coding are
+			// semantically equivalent, so they match weakly.
+			candidate = stripEncodedETag(strings.TrimPrefix(candidate, "W/"))
+			if candidate == stripEncodedETag(strings.TrimPrefix(etag, "W/")) {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type versionedResponse struct {
+	Name    string    `json:"name"`
+	Version string    `json:"-"`
+	Updated time.Time `json:"-"`
+}
+
+func (r versionedResponse) ETag() string            { return r.Version }
+func (r versionedResponse) LastModified() time.Time { return r.Updated }
+
+func TestConditionalGET(t *testing.T) {
+	updated := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	opts := DefaultOpts
+	opts.ConditionalGET = true
+
+	r := NewRouter(opts)
+	r.Get("/plain", Wrap(func(ctx context.Context, req None) (echoResponse, error) {
+		return echoResponse{What: "hi"}, nil
+	}))
+	r.Get("/versioned", Wrap(func(ctx context.Context, req None) (versionedResponse, error) {
+		return versionedResponse{Name: "hi", Version: "v1", Updated: updated}, nil
+	}))
+
+	plainETag := computeETag([]byte("{\"what\":\"hi\"}\n"))
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		status int
+		etag   string
+		body   string
+	}{
+		{
+			name:   "computed ETag",
+			path:   "/plain",
+			status: 200,
+			etag:   plainETag,
+			body:   `{"what":"hi"}`,
+		},
+		{
+			name:   "matching If-None-Match",
+			path:   "/plain",
+			header: http.Header{"If-None-Match": {`"other", ` + plainETag}},
+			status: 304,
+			etag:   plainETag,
+		},
+		{
+			name:   "weak If-None-Match",
+			path:   "/plain",
+			header: http.Header{"If-None-Match": {"W/" + plainETag}},
+			status: 304,
+			etag:   plainETag,
+		},
+		{
+			name:   "stale If-None-Match",
+			path:   "/plain",
+			header: http.Header{"If-None-Match": {`"other"`}},
+			status: 200,
+			etag:   plainETag,
+			body:   `{"what":"hi"}`,
+		},
+		{
+			name:   "ETagger",
+			path:   "/versioned",
+			header: http.Header{"If-None-Match": {`"v1"`}},
+			status: 304,
+			etag:   `"v1"`,
+		},
+		{
+			name:   "If-Modified-Since",
+			path:   "/versioned",
+			header: http.Header{"If-Modified-Since": {updated.Format(http.TimeFormat)}},
+			status: 304,
+			etag:   `"v1"`,
+		},
+		{
+			name:   "If-Modified-Since ignored with If-None-Match",
+			path:   "/versioned",
+			header: http.Header{"If-Modified-Since": {updated.Format(http.TimeFormat)}, "If-None-Match": {`"v0"`}},
+			status: 200,
+			etag:   `"v1"`,
+			body:   `{"name":"hi"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.path, nil)
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if etag := rec.Header().Get("ETag"); etag != test.etag {
+				t.Errorf("expected ETag %s, got %s", test.etag, etag)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+		})
+	}
+}
@@ -1,10 +1,11 @@
 package hrt
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pkg/errors"
@@ -34,9 +35,12 @@ func (e MethodDecoder) Decode(r *http.Request, v any) error {
 }
 
 // URLDecoder decodes chi.URLParams and url.Values into a struct. It only does
-// Decoding; the Encode method is a no-op. The decoder makes no effort to
-// traverse the struct and decode nested structs. If neither a chi.URLParam nor
-// a url.Value is found for a field, the field is left untouched.
+// Decoding; the Encode method is a no-op. The fields of embedded structs are
+// decoded as if they were declared in the outer struct. Other struct fields
+// are not traversed: they are decoded as a single value, which requires a json
+// tag, a parser or an encoding.TextUnmarshaler, and are otherwise ignored. If
+// neither a chi.URLParam nor a url.Value is found for a field, the field is
+// left untouched.
 //
 // The following tags are supported:
 //
@@ -46,80 +50,282 @@ func (e MethodDecoder) Decode(r *http.Request, v any) error {
 //   - `schema` - similar to `form`, exists for compatibility with gorilla/schema.
 //   - `json` - uses either chi.URLParam or r.FormValue to decode the value.
 //     If the value is provided within the form, then it is unmarshaled as JSON
-//     into the field unless the type is a string. If the value is provided within
-//     the URL, then it is unmarshaled as a primitive value.
+//     into the field unless the type is a string or has a parser. If the value
+//     is provided within the URL, then it is unmarshaled as a primitive value.
+//
+// Slices of primitive values take every value of their key, each of which may
+// contain several comma-separated elements, so ?sort=name&sort=id and
+// ?sort=name,id both decode to []string{"name", "id"}. Slices with a json tag
+// are unmarshaled as JSON from the form instead.
 //
 // If a struct field has no tag, it is assumed to be the same as the field name.
 // If a struct field has a tag, then only that tag is used.
 //
+// Values are parsed according to the default ParsePolicy, which can be changed
+// using NewURLDecoder or per field using tag options.
+//
+// The tags of a type are parsed once and cached, so decoding a request does
+// not inspect them again. Wrap checks the tags of its request type up front.
+//
 // # Example
 //
-// The following Go type would be decoded to have 2 URL parameters:
+// The following Go type is decoded from up to 6 values:
 //
 //	type Data struct {
-//	    ID  string
-//	    Num int `url:"num"`
+//	    hrt.PageRequest
+//	    ID     string
+//	    Num    int    `url:"num"`
+//	    Filter Filter `json:"filter"`
 //	    Nested struct {
 //	        ID string
 //	    }
 //	}
+//
+// Cursor, Limit and Offset are decoded from the query like in PageRequest
+// itself. ID is decoded from the URL parameter or form value named ID, ignoring
+// case, and Num from the num URL parameter. Filter is unmarshaled from the
+// filter form value as JSON. Nested is left untouched.
 var URLDecoder Decoder = urlDecoder{}
 
-type urlDecoder struct{}
+// NewURLDecoder creates a URLDecoder with the given options.
+func NewURLDecoder(opts URLDecoderOpts) Decoder {
+	return urlDecoder{opts}
+}
 
-func (d urlDecoder) Decode(r *http.Request, v any) error {
-	return rfutil.EachStructField(v, func(rft reflect.StructField, rfv reflect.Value) error {
-		for _, tag := range []string{"form", "query", "schema"} {
-			if tagValue := rft.Tag.Get(tag); tagValue != "" {
-				val := r.FormValue(tagValue)
-				return rfutil.SetPrimitiveFromString(rft.Type, rfv, val)
-			}
-		}
+// URLDecoderOpts contains options for NewURLDecoder.
+type URLDecoderOpts struct {
+	// Policy is the default parsing policy of all fields. It can be
+	// overridden per field using tag options.
+	Policy ParsePolicy
+	// Parsers contains parsers for types that cannot be parsed otherwise or
+	// that should be parsed differently. They take precedence over the
+	// Policy and over encoding.TextUnmarshaler.
+	Parsers Parsers
+}
 
-		if tagValue := rft.Tag.Get("url"); tagValue != "" {
-			val := chi.URLParam(r, tagValue)
-			return rfutil.SetPrimitiveFromString(rft.Type, rfv, val)
-		}
+// Parsers maps types to functions that parse them from strings. Use AddParser
+// to add a parser. For example:
+//
+//	parsers := hrt.Parsers{}
+//	hrt.AddParser(parsers, uuid.Parse)
+//	hrt.AddParser(parsers, netip.ParseAddr)
+//
+//	dec := hrt.NewURLDecoder(hrt.URLDecoderOpts{Parsers: parsers})
+//
+// A parser for type T is also used for fields of type *T.
+type Parsers map[reflect.Type]func(string) (any, error)
+
+// AddParser adds a parser for values of type T to p, replacing any existing
+// parser of that type.
+func AddParser[T any](p Parsers, parse func(string) (T, error)) {
+	p[reflect.TypeFor[T]()] = func(s string) (any, error) {
+		v, err := parse(s)
+		return v, err
+	}
+}
+
+func (p Parsers) has(rt reflect.Type) bool {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	_, ok := p[rt]
+	return ok
+}
+
+// ParsePolicy controls how URLDecoder parses the string values of URL
+// parameters and form values into fields. The zero value is the default
+// policy.
+//
+// The policy of a single field can be overridden using options in its tag,
+// e.g. `query:"verbose,bool=presence"`:
+//
+//   - `bool=strict` or `bool=presence` sets Bool.
+//   - `empty=keep` or `empty=zero` sets Empty.
+//   - `int=base10` or `int=prefixed` sets IntPrefixes.
+//   - `layout=rfc3339`, `layout=date`, `layout=unix` or `layout=unixmilli`
+//     sets TimeLayout to time.RFC3339, "2006-01-02", TimeLayoutUnix or
+//     TimeLayoutUnixMilli respectively.
+//
+// Floats are always parsed using strconv.ParseFloat, which does not depend on
+// the locale. time.Duration values are parsed using time.ParseDuration, e.g.
+// ?timeout=1h30m.
+type ParsePolicy struct {
+	// Bool controls how bools are parsed.
+	Bool BoolPolicy
+	// Empty controls how present but empty values are handled, e.g. ?name=.
+	Empty EmptyPolicy
+	// IntPrefixes allows ints and uints to be written with a base prefix
+	// (0x, 0o, 0b) and with underscores, as in Go literals.
+	IntPrefixes bool
+	// TimeLayout is the layout of time.Time values. It is either a layout
+	// for time.Parse, TimeLayoutUnix or TimeLayoutUnixMilli. If empty, times
+	// are parsed as RFC 3339.
+	TimeLayout string
+}
+
+// Special values of ParsePolicy.TimeLayout.
+const (
+	// TimeLayoutUnix represents times as Unix timestamps in seconds.
+	TimeLayoutUnix = rfutil.TimeLayoutUnix
+	// TimeLayoutUnixMilli represents times as Unix timestamps in
+	// milliseconds.
+	TimeLayoutUnixMilli = rfutil.TimeLayoutUnixMilli
+)
+
+var timeLayoutOptions = map[string]string{
+	"rfc3339":   time.RFC3339,
+	"date":      "2006-01-02",
+	"unix":      TimeLayoutUnix,
+	"unixmilli": TimeLayoutUnixMilli,
+}
+
+// BoolPolicy controls how bools are parsed.
+type BoolPolicy uint8
+
+const (
+	// BoolStrict parses bools using strconv.ParseBool, so ?active=false is
+	// false and ?active=nope is an error. Empty values are handled according
+	// to the EmptyPolicy.
+	BoolStrict BoolPolicy = iota
+	// BoolPresence treats a present but empty value as true, so ?verbose is
+	// true. Non-empty values are parsed like BoolStrict.
+	BoolPresence
+)
+
+// EmptyPolicy controls how present but empty values are handled.
+type EmptyPolicy uint8
+
+const (
+	// EmptyKeep leaves the field untouched, as if the value was absent.
+	EmptyKeep EmptyPolicy = iota
+	// EmptyZero sets the field to its zero value, which allows clearing a
+	// field that has a default value.
+	EmptyZero
+)
+
+func (p ParsePolicy) parseOpts() rfutil.ParseOpts {
+	return rfutil.ParseOpts{
+		BoolPresence: p.Bool == BoolPresence,
+		EmptyZero:    p.Empty == EmptyZero,
+		IntPrefixes:  p.IntPrefixes,
+		TimeLayout:   p.TimeLayout,
+	}
+}
+
+// withTagOptions returns the policy with the given comma-separated tag
+// options applied. Options without a value, such as omitempty, are ignored.
+func (p ParsePolicy) withTagOptions(opts string) (ParsePolicy, error) {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
 
-		if tagValue := rft.Tag.Get("json"); tagValue != "" {
-			if val := chi.URLParam(r, tagValue); val != "" {
-				return rfutil.SetPrimitiveFromString(rft.Type, rfv, val)
-			}
-
-			val := r.FormValue(tagValue)
-			if rft.Type.Kind() == reflect.String {
-				rfv.SetString(val)
-				return nil
-			}
-
-			jsonValue := reflect.New(rft.Type)
-			if err := json.Unmarshal([]byte(val), jsonValue.Interface()); err != nil {
-				return errors.Wrap(err, "failed to unmarshal JSON")
-			}
-			rfv.Set(jsonValue.Elem())
+		k, v, ok := strings.Cut(opt, "=")
+		if !ok {
+			continue
 		}
 
-		// Search for the URL parameters manually.
-		if rctx := chi.RouteContext(r.Context()); rctx != nil {
-			for i, k := range rctx.URLParams.Keys {
-				if strings.EqualFold(k, rft.Name) {
-					return rfutil.SetPrimitiveFromString(rfv.Type(), rfv, rctx.URLParams.Values[i])
-				}
-			}
+		switch {
+		case k == "bool" && v == "strict":
+			p.Bool = BoolStrict
+		case k == "bool" && v == "presence":
+			p.Bool = BoolPresence
+		case k == "empty" && v == "keep":
+			p.Empty = EmptyKeep
+		case k == "empty" && v == "zero":
+			p.Empty = EmptyZero
+		case k == "int" && v == "base10":
+			p.IntPrefixes = false
+		case k == "int" && v == "prefixed":
+			p.IntPrefixes = true
+		case k == "layout" && timeLayoutOptions[v] != "":
+			p.TimeLayout = timeLayoutOptions[v]
+		default:
+			return p, fmt.Errorf("unknown tag option %q", opt)
 		}
+	}
+	return p, nil
+}
+
+type urlDecoder struct {
+	opts URLDecoderOpts
+}
+
+func (d urlDecoder) Decode(r *http.Request, v any) error {
+	if err := d.decode(r, v); err != nil {
+		return err
+	}
+	return checkEnums(v)
+}
+
+func (d urlDecoder) decode(r *http.Request, v any) error {
+	if u, ok := v.(URLDecodable); ok {
+		return u.DecodeURL(newURLDecodeState(r, d.opts))
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return errors.New("invalid value")
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return errors.New("value is not a struct")
+	}
 
-		// Trigger form parsing.
-		r.FormValue("")
+	plan, err := decodePlanOf(rv.Type(), d.opts.Policy)
+	if err != nil {
+		return err
+	}
+
+	return plan.decode(r, rv, d.opts.Parsers)
+}
 
-		// Search for URL form values manually.
-		for k, v := range r.Form {
-			if strings.EqualFold(k, rft.Name) {
-				return rfutil.SetPrimitiveFromString(rfv.Type(), rfv, v[0])
-			}
+// fieldPolicy returns the parsing policy of a field with the tag options of its
+// URL tag applied.
+func fieldPolicy(f reflect.StructField, policy ParsePolicy) (ParsePolicy, error) {
+	for _, tag := range []string{"form", "query", "schema", "url"} {
+		if name, opts := parseTag(f.Tag.Get(tag)); name != "" {
+			return policy.withTagOptions(opts)
 		}
+	}
+	return policy, nil
+}
+
+// parseTag splits a struct tag value into its name and options.
+func parseTag(tag string) (name, opts string) {
+	name, opts, _ = strings.Cut(tag, ",")
+	return name, opts
+}
 
-		return nil // ignore
-	})
+// formValue returns the form value with the given key and whether it is
+// present at all.
+func formValue(r *http.Request, key string) (string, bool) {
+	val := r.FormValue(key)
+	_, ok := r.Form[key]
+	return val, ok
+}
+
+// formValues returns all form values with the given key and whether it is
+// present at all.
+func formValues(r *http.Request, key string) ([]string, bool) {
+	// Trigger form parsing.
+	r.FormValue("")
+
+	vals, ok := r.Form[key]
+	return vals, ok
+}
+
+// urlParamFrom returns the chi URL parameter with the given key and whether it
+// is present at all.
+func urlParamFrom(rctx *chi.Context, key string) (string, bool) {
+	if rctx == nil {
+		return "", false
+	}
+	for i := len(rctx.URLParams.Keys) - 1; i >= 0; i-- {
+		if rctx.URLParams.Keys[i] == key {
+			return rctx.URLParams.Values[i], true
+		}
+	}
+	return "", false
 }
 
 // DecoderWithValidator wraps an encoder with one that calls Validate() on the
@@ -1,11 +1,18 @@
 package hrt
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func TestURLDecoder(t *testing.T) {
@@ -76,6 +83,336 @@ func TestURLDecoder(t *testing.T) {
 	}
 }
 
+func TestURLDecoder_parsePolicy(t *testing.T) {
+	type Flags struct {
+		Active  bool    `query:"active"`
+		Verbose bool    `query:"verbose,bool=presence"`
+		Name    string  `query:"name"`
+		Limit   int     `query:"limit,empty=zero"`
+		Mask    uint32  `query:"mask,int=prefixed"`
+		Ratio   float64 `query:"ratio"`
+	}
+
+	defaults := Flags{Name: "default", Limit: 20}
+
+	tests := []struct {
+		name   string
+		policy ParsePolicy
+		input  url.Values
+		expect result[Flags]
+	}{
+		{
+			name:   "absent",
+			input:  url.Values{},
+			expect: okResult(defaults),
+		},
+		{
+			name:   "strict bool",
+			input:  url.Values{"active": {"false"}},
+			expect: okResult(defaults),
+		},
+		{
+			name:   "strict bool true",
+			input:  url.Values{"active": {"1"}},
+			expect: okResult(Flags{Active: true, Name: "default", Limit: 20}),
+		},
+		{
+			name:   "invalid strict bool",
+			input:  url.Values{"active": {"yes"}},
+			expect: result[Flags]{value: defaults, error: `invalid bool: strconv.ParseBool: parsing "yes": invalid syntax`},
+		},
+		{
+			name:   "presence bool",
+			input:  url.Values{"verbose": {""}},
+			expect: okResult(Flags{Verbose: true, Name: "default", Limit: 20}),
+		},
+		{
+			name:   "presence bool false",
+			input:  url.Values{"verbose": {"false"}},
+			expect: okResult(defaults),
+		},
+		{
+			name:   "empty keeps",
+			input:  url.Values{"name": {""}},
+			expect: okResult(defaults),
+		},
+		{
+			name:   "empty zero per field",
+			input:  url.Values{"limit": {""}},
+			expect: okResult(Flags{Name: "default"}),
+		},
+		{
+			name:   "empty zero per decoder",
+			policy: ParsePolicy{Empty: EmptyZero},
+			input:  url.Values{"name": {""}},
+			expect: okResult(Flags{Limit: 20}),
+		},
+		{
+			name:   "prefixed int",
+			input:  url.Values{"mask": {"0xff_ff"}},
+			expect: okResult(Flags{Mask: 0xffff, Name: "default", Limit: 20}),
+		},
+		{
+			name:   "prefixed int per decoder",
+			policy: ParsePolicy{IntPrefixes: true},
+			input:  url.Values{"limit": {"0b101"}},
+			expect: okResult(Flags{Name: "default", Limit: 5}),
+		},
+		{
+			name:   "base 10 int",
+			input:  url.Values{"limit": {"0x10"}},
+			expect: result[Flags]{value: defaults, error: `invalid int: strconv.ParseInt: parsing "0x10": invalid syntax`},
+		},
+		{
+			name:   "locale-free float",
+			input:  url.Values{"ratio": {"1,5"}},
+			expect: result[Flags]{value: defaults, error: `invalid float: strconv.ParseFloat: parsing "1,5": invalid syntax`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{
+				Form: test.input,
+			}
+
+			got := defaults
+			err := NewURLDecoder(URLDecoderOpts{Policy: test.policy}).Decode(req, &got)
+			res := combineResult(got, err)
+
+			if !reflect.DeepEqual(test.expect, res) {
+				t.Errorf("unexpected test result:\n"+
+					"expected: %v\n"+
+					"got:      %v\n", test.expect, res)
+			}
+		})
+	}
+}
+
+func TestURLDecoder_invalidTagOption(t *testing.T) {
+	type Bad struct {
+		Active bool `query:"active,bool=maybe"`
+	}
+
+	req := &http.Request{Form: url.Values{}}
+	err := URLDecoder.Decode(req, &Bad{})
+
+	if expect := `field Active: unknown tag option "bool=maybe"`; err == nil || err.Error() != expect {
+		t.Errorf("unexpected error:\n expected: %s\n got: %v", expect, err)
+	}
+}
+
+func TestWrap_invalidTagOption(t *testing.T) {
+	type Paging struct {
+		Limit int `query:"limit,int=hex"`
+	}
+	type Bad struct {
+		Paging
+	}
+
+	defer func() {
+		expect := `hrt: invalid request type hrt.Bad: field Limit: unknown tag option "int=hex"`
+		if got := recover(); got != expect {
+			t.Errorf("unexpected panic:\n expected: %s\n got: %v", expect, got)
+		}
+	}()
+
+	Wrap(func(ctx context.Context, req Bad) (None, error) {
+		return Empty, nil
+	})
+}
+
+func TestURLDecoder_cachedPlan(t *testing.T) {
+	type Paging struct {
+		Limit int `query:"limit"`
+	}
+	type Request struct {
+		ID int `url:"id"`
+		Paging
+		Sort string
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "7")
+
+	req := &http.Request{Form: url.Values{"limit": {"5"}, "SORT": {"name"}}}
+	req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, rctx))
+
+	// Decode twice to exercise the cached plan.
+	for i := 0; i < 2; i++ {
+		var got Request
+		if err := URLDecoder.Decode(req, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := Request{ID: 7, Paging: Paging{Limit: 5}, Sort: "name"}
+		if got != expect {
+			t.Errorf("unexpected request:\n expected: %+v\n got: %+v", expect, got)
+		}
+	}
+}
+
+func TestURLDecoder_slices(t *testing.T) {
+	type Request struct {
+		IDs    []int        `url:"ids"`
+		Sort   []string     `query:"sort"`
+		Status []itemStatus `query:"status,empty=zero"`
+		Labels []string
+	}
+
+	tests := []struct {
+		name   string
+		param  string
+		query  url.Values
+		expect result[Request]
+	}{
+		{
+			name:   "repeated",
+			query:  url.Values{"sort": {"name", "id"}, "LABELS": {"a", "b"}},
+			expect: okResult(Request{Sort: []string{"name", "id"}, Status: []itemStatus{"active"}, Labels: []string{"a", "b"}}),
+		},
+		{
+			name:   "comma separated",
+			param:  "1,2",
+			query:  url.Values{"sort": {"name,id", "age"}, "status": {"archived,active"}},
+			expect: okResult(Request{IDs: []int{1, 2}, Sort: []string{"name", "id", "age"}, Status: []itemStatus{"archived", "active"}}),
+		},
+		{
+			name:   "empty",
+			query:  url.Values{"sort": {""}, "status": {""}},
+			expect: okResult(Request{Sort: []string{"keep"}}),
+		},
+		{
+			name:   "invalid element",
+			param:  "1,x",
+			expect: result[Request]{error: `element 1: invalid int: strconv.ParseInt: parsing "x": invalid syntax`},
+		},
+		{
+			name:   "invalid enum",
+			query:  url.Values{"status": {"active,deleted"}},
+			expect: result[Request]{error: `400: invalid status[1] "deleted" (expected one of active, archived)`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			if test.param != "" {
+				rctx.URLParams.Add("ids", test.param)
+			}
+
+			req := &http.Request{Form: test.query}
+			req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, rctx))
+
+			got := Request{Sort: []string{"keep"}, Status: []itemStatus{"active"}}
+			err := URLDecoder.Decode(req, &got)
+
+			res := combineResult(got, err)
+			if res.error != "" {
+				res.value = Request{}
+			}
+			if !reflect.DeepEqual(test.expect, res) {
+				t.Errorf("unexpected result:\n expected: %+v\n got: %+v", test.expect, res)
+			}
+		})
+	}
+}
+
+type accountID int
+
+func parseAccountID(s string) (accountID, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "acct_"))
+	if err != nil || !strings.HasPrefix(s, "acct_") {
+		return 0, fmt.Errorf("malformed account ID %q", s)
+	}
+	return accountID(n), nil
+}
+
+func TestURLDecoder_parsers(t *testing.T) {
+	type Request struct {
+		ID      accountID   `url:"id"`
+		Parent  *accountID  `query:"parent"`
+		Owner   accountID   `json:"owner"`
+		Addr    netip.Addr  `query:"addr"`
+		Limited accountList `query:"limited"`
+	}
+
+	parsers := Parsers{}
+	AddParser(parsers, parseAccountID)
+	AddParser(parsers, func(s string) (netip.Addr, error) {
+		// Allow brackets around addresses, which UnmarshalText rejects.
+		return netip.ParseAddr(strings.Trim(s, "[]"))
+	})
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		input  url.Values
+		expect result[Request]
+	}{
+		{
+			name:   "url and query",
+			params: map[string]string{"id": "acct_1"},
+			input:  url.Values{"parent": {"acct_2"}, "owner": {"acct_3"}, "addr": {"[::1]"}},
+			expect: okResult(Request{
+				ID:     1,
+				Parent: ptrTo(accountID(2)),
+				Owner:  3,
+				Addr:   netip.MustParseAddr("::1"),
+			}),
+		},
+		{
+			name:   "invalid",
+			params: map[string]string{"id": "1"},
+			input:  url.Values{},
+			expect: result[Request]{error: `invalid hrt.accountID: malformed account ID "1"`},
+		},
+		{
+			name:   "text unmarshaler",
+			input:  url.Values{"limited": {"acct_4"}},
+			expect: okResult(Request{Limited: accountList{4}}),
+		},
+	}
+
+	dec := NewURLDecoder(URLDecoderOpts{Parsers: parsers})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			for k, v := range test.params {
+				rctx.URLParams.Add(k, v)
+			}
+
+			req := &http.Request{Form: test.input}
+			req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, rctx))
+
+			var got Request
+			err := dec.Decode(req, &got)
+			res := combineResult(got, err)
+
+			if !reflect.DeepEqual(test.expect, res) {
+				t.Errorf("unexpected test result:\n"+
+					"expected: %v\n"+
+					"got:      %v\n", test.expect, res)
+			}
+		})
+	}
+}
+
+// accountList is a list of account IDs that parses itself without a parser.
+type accountList []accountID
+
+func (l *accountList) UnmarshalText(b []byte) error {
+	for _, s := range strings.Split(string(b), ",") {
+		id, err := parseAccountID(s)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
 type result[T any] struct {
 	value T
 	error string
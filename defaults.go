@@ -0,0 +1,174 @@
+package hrt
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+type fieldDefault struct {
+	index []int
+	value reflect.Value
+}
+
+var fieldDefaults sync.Map // reflect.Type -> []fieldDefault or error
+
+// defaultsOf returns the parsed defaults of the struct type rt.
+func defaultsOf(rt reflect.Type) ([]fieldDefault, error) {
+	if v, ok := fieldDefaults.Load(rt); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.([]fieldDefault), nil
+	}
+
+	defaults, err := collectDefaults(rt, nil, map[reflect.Type]bool{})
+	if err != nil {
+		fieldDefaults.Store(rt, err)
+		return nil, err
+	}
+
+	fieldDefaults.Store(rt, defaults)
+	return defaults, nil
+}
+
+func collectDefaults(rt reflect.Type, index []int, visited map[reflect.Type]bool) ([]fieldDefault, error) {
+	if rt.Kind() != reflect.Struct || visited[rt] {
+		return nil, nil
+	}
+	visited[rt] = true
+	defer delete(visited, rt)
+
+	var defaults []fieldDefault
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		tag, ok := f.Tag.Lookup("default")
+		if !ok {
+			nested, err := collectDefaults(f.Type, fieldIndex, visited)
+			if err != nil {
+				return nil, err
+			}
+			defaults = append(defaults, nested...)
+			continue
+		}
+
+		policy, err := fieldPolicy(f, ParsePolicy{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s.%s", rt, f.Name)
+		}
+
+		v, err := parseDefault(f.Type, tag, policy.parseOpts())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid default of field %s.%s", rt, f.Name)
+		}
+
+		defaults = append(defaults, fieldDefault{fieldIndex, v})
+	}
+
+	return defaults, nil
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// parseDefault parses the default value s of a field of type rt.
+func parseDefault(rt reflect.Type, s string, opts rfutil.ParseOpts) (reflect.Value, error) {
+	v := reflect.New(rt).Elem()
+
+	if rt.Kind() == reflect.Slice && !reflect.PointerTo(rt).Implements(textUnmarshalerType) {
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+
+		v.Set(reflect.MakeSlice(rt, len(parts), len(parts)))
+		for i, part := range parts {
+			elem, err := parseDefault(rt.Elem(), strings.TrimSpace(part), opts)
+			if err != nil {
+				return v, errors.Wrapf(err, "element %d", i)
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+	}
+
+	if !isParsablePrimitive(rt) {
+		return v, fmt.Errorf("unsupported type %s", rt)
+	}
+
+	opts.EmptyZero = true
+	err := rfutil.SetFromString(rt, v, s, true, opts)
+	return v, err
+}
+
+// isParsablePrimitive returns true if rfutil.SetFromString supports rt.
+func isParsablePrimitive(rt reflect.Type) bool {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if reflect.PointerTo(rt).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isParsableSlice returns true if rt is a slice of types that
+// isParsablePrimitive supports. URLDecoder decodes such slices from repeated or
+// comma-separated values.
+func isParsableSlice(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Slice &&
+		!reflect.PointerTo(rt).Implements(textUnmarshalerType) &&
+		isParsablePrimitive(rt.Elem())
+}
+
+// setDefaults sets the default values of the struct pointed to by v. An
+// invalid default tag results in a 500 error, since it is a programming error.
+func setDefaults(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	rv = rv.Elem()
+
+	defaults, err := defaultsOf(rv.Type())
+	if err != nil {
+		return WrapHTTPError(http.StatusInternalServerError, err)
+	}
+
+	for _, d := range defaults {
+		field := rv.FieldByIndex(d.index)
+
+		// Copy slices and pointers so that handlers cannot modify the default.
+		value := d.value
+		switch value.Kind() {
+		case reflect.Slice:
+			value = reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			reflect.Copy(value, d.value)
+		case reflect.Ptr:
+			value = reflect.New(value.Type().Elem())
+			value.Elem().Set(d.value.Elem())
+		}
+		field.Set(value)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package hrt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type defaultsRequest struct {
+	Limit   int           `query:"limit" json:"limit" default:"20"`
+	Sort    []string      `query:"sort" json:"sort" default:"name, id"`
+	Timeout time.Duration `query:"timeout" json:"timeout" default:"30s"`
+	Since   time.Time     `query:"since" json:"since" default:"2020-01-01T00:00:00Z"`
+	Ratio   *float64      `query:"ratio" json:"ratio" default:"0.5"`
+	Nested  struct {
+		Enabled bool `query:"enabled" json:"enabled" default:"true"`
+	} `json:"nested"`
+}
+
+func TestDefaults(t *testing.T) {
+	var got []defaultsRequest
+	h := Wrap(func(ctx context.Context, req defaultsRequest) (None, error) {
+		got = append(got, req)
+		got[len(got)-1].Sort = append([]string(nil), req.Sort...)
+		req.Sort[0] = "mutated"
+		return Empty, nil
+	})
+
+	all := defaultsRequest{
+		Limit:   20,
+		Sort:    []string{"name", "id"},
+		Timeout: 30 * time.Second,
+		Since:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Ratio:   ptrTo(0.5),
+	}
+	all.Nested.Enabled = true
+
+	overridden := all
+	overridden.Limit = 5
+	overridden.Sort = []string{"age"}
+
+	sorted := all
+	sorted.Sort = []string{"created", "id"}
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		expect defaultsRequest
+	}{
+		{"GET", "/", "", all},
+		{"GET", "/?limit=5&sort=age", "", overridden},
+		{"GET", "/?sort=created&sort=id", "", sorted},
+		{"GET", "/?sort=created,id", "", sorted},
+		{"GET", "/?sort=", "", all},
+		{"POST", "/", `{}`, all},
+		{"POST", "/", `{"limit": 5, "sort": ["age"]}`, overridden},
+	}
+
+	for _, test := range tests {
+		got = nil
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+
+		if rec.Code != 200 || len(got) != 1 {
+			t.Errorf("%s %s %s: unexpected status %d: %s", test.method, test.target, test.body, rec.Code, rec.Body)
+			continue
+		}
+
+		if !reflect.DeepEqual(got[0], test.expect) {
+			t.Errorf("%s %s %s: unexpected request:\n expected: %+v\n got: %+v",
+				test.method, test.target, test.body, test.expect, got[0])
+		}
+	}
+
+	// The default must not have been modified by the handler.
+	got = nil
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(got) != 1 {
+		t.Fatalf("expected handler to be called")
+	}
+	if defaults, _ := defaultsOf(reflect.TypeFor[defaultsRequest]()); defaults[1].value.Index(0).String() != "name" {
+		t.Errorf("default was modified by handler")
+	}
+}
+
+func TestDefaults_invalid(t *testing.T) {
+	type badRequest struct {
+		Limit int `query:"limit" default:"many"`
+	}
+
+	handle := func(ctx context.Context, req badRequest) (None, error) {
+		return Empty, nil
+	}
+
+	t.Run("wrap", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		Wrap(handle)
+	})
+
+	t.Run("serve", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Handler[badRequest, None](handle).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if rec.Code != 500 {
+			t.Errorf("expected status 500, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
+
+func TestDefaults_schema(t *testing.T) {
+	g := NewSchemaGenerator()
+	g.Generate(reflect.TypeFor[defaultsRequest]())
+
+	b, _ := json.Marshal(g.Schemas["defaultsRequest"].Properties)
+	expect := `{
+		"limit": {"type": "integer", "format": "int64", "default": 20},
+		"sort": {"type": "array", "items": {"type": "string"}, "default": ["name", "id"]},
+		"timeout": {"type": "integer", "format": "int64", "default": "30s"},
+		"since": {"type": "string", "format": "date-time", "default": "2020-01-01T00:00:00Z"},
+		"ratio": {"type": "number", "format": "double", "default": 0.5},
+		"nested": {
+			"type": "object",
+			"properties": {"enabled": {"type": "boolean", "default": true}},
+			"required": ["enabled"]
+		}
+	}`
+	assertJSONEqual(t, "defaults", expect, json.RawMessage(b))
+}
@@ -11,12 +11,14 @@ import (
 // requests using the query string and URL parameter; everything else uses JSON.
 //
 // For the sake of being RESTful, we use a URLDecoder for GET requests.
-// Everything else will be decoded as JSON.
+// PATCH requests are decoded using PatchDecoder, which also accepts patch
+// documents. Everything else will be decoded as JSON.
 var DefaultEncoder = CombinedEncoder{
 	Encoder: EncoderWithValidator(JSONEncoder),
 	Decoder: DecoderWithValidator(MethodDecoder{
-		"GET": URLDecoder,
-		"*":   JSONEncoder,
+		"GET":   URLDecoder,
+		"PATCH": PatchDecoder,
+		"*":     JSONEncoder,
 	}),
 }
 
@@ -68,11 +70,24 @@ type jsonEncoder struct{}
 
 func (e jsonEncoder) Encode(w http.ResponseWriter, v any) error {
 	w.Header().Set("Content-Type", "application/json")
+
+	if a, ok := v.(JSONAppender); ok {
+		b, err := a.AppendJSON(nil)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+
 	return json.NewEncoder(w).Encode(v)
 }
 
 func (e jsonEncoder) Decode(r *http.Request, v any) error {
-	return json.NewDecoder(r.Body).Decode(v)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return err
+	}
+	return checkEnums(v)
 }
 
 // Validator describes a type that can validate itself.
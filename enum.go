@@ -0,0 +1,210 @@
+package hrt
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Enum is implemented by types whose values are restricted to a fixed set,
+// usually string types with a list of constants. Enum must not depend on the
+// value of its receiver. The zero value is considered unset and is always
+// allowed; to require a value, check it in Validate.
+//
+// URLDecoder and JSONEncoder reject requests with values that are not in the
+// set, and SchemaGenerator emits the set as the schema's enum.
+//
+// # Example
+//
+//	type Status string
+//
+//	const (
+//	    StatusActive   Status = "active"
+//	    StatusArchived Status = "archived"
+//	)
+//
+//	func (Status) Enum() []string {
+//	    return []string{string(StatusActive), string(StatusArchived)}
+//	}
+type Enum interface {
+	Enum() []string
+}
+
+var enumType = reflect.TypeFor[Enum]()
+
+// ValidateEnum returns an error if v is not one of its allowed values. It is
+// meant to be used in Validate methods.
+func ValidateEnum(v Enum) error {
+	return validateEnum("value", reflect.ValueOf(v))
+}
+
+// EnumError is returned when a value is not one of the allowed values of an
+// Enum. Decoders wrap it in a 400 Bad Request error.
+type EnumError struct {
+	// Field is the name of the field, or "value" if unknown.
+	Field string
+	// Value is the invalid value.
+	Value string
+	// Allowed is the list of allowed values.
+	Allowed []string
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("invalid %s %q (expected one of %s)",
+		e.Field, e.Value, strings.Join(e.Allowed, ", "))
+}
+
+func validateEnum(field string, v reflect.Value) error {
+	if !v.IsValid() || !v.CanInterface() || v.IsZero() {
+		return nil
+	}
+
+	e, ok := v.Interface().(Enum)
+	if !ok {
+		return nil
+	}
+
+	s := enumString(v)
+	allowed := e.Enum()
+	for _, a := range allowed {
+		if a == s {
+			return nil
+		}
+	}
+
+	return &EnumError{Field: field, Value: s, Allowed: allowed}
+}
+
+func enumString(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+var enumTypes sync.Map // reflect.Type -> bool
+
+// hasEnums returns true if values of t may contain an Enum.
+func hasEnums(t reflect.Type) bool {
+	if v, ok := enumTypes.Load(t); ok {
+		return v.(bool)
+	}
+
+	has := findEnums(t, map[reflect.Type]bool{})
+	enumTypes.Store(t, has)
+	return has
+}
+
+func findEnums(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return t.Implements(enumType) || findEnums(t.Elem(), visited)
+	case reflect.Struct:
+		if t.Implements(enumType) {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if findEnums(t.Field(i).Type, visited) {
+				return true
+			}
+		}
+		return false
+	default:
+		return t.Implements(enumType)
+	}
+}
+
+// checkEnums validates all Enum values within v, which is usually a pointer
+// to a decoded request. Fields are named after their tags.
+func checkEnums(v any) error {
+	if c, ok := v.(EnumChecker); ok {
+		if err := c.CheckEnums(); err != nil {
+			return WrapHTTPError(http.StatusBadRequest, err)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !hasEnums(rv.Type()) {
+		return nil
+	}
+	if err := checkEnumValue("value", rv); err != nil {
+		return WrapHTTPError(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func checkEnumValue(name string, v reflect.Value) error {
+	if !hasEnums(v.Type()) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkEnumValue(name, v.Elem())
+	}
+
+	if v.Type().Implements(enumType) {
+		return validateEnum(name, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkEnumValue(fmt.Sprintf("%s[%d]", name, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkEnumValue(fmt.Sprintf("%s[%v]", name, iter.Key()), iter.Value()); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() && !f.Anonymous {
+				continue
+			}
+
+			fieldName := fieldTagName(f)
+			if name != "value" && !f.Anonymous {
+				fieldName = name + "." + fieldName
+			} else if f.Anonymous {
+				fieldName = name
+			}
+
+			if err := checkEnumValue(fieldName, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldTagName returns the name of a request field as given by its tags.
+func fieldTagName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "form", "schema", "url"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
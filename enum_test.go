@@ -0,0 +1,117 @@
+package hrt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type itemStatus string
+
+func (itemStatus) Enum() []string { return []string{"active", "archived"} }
+
+type enumRequest struct {
+	Status itemStatus  `query:"status"`
+	Kind   *itemStatus `url:"kind"`
+}
+
+type enumBody struct {
+	Filter struct {
+		Statuses []itemStatus `json:"statuses"`
+	} `json:"filter"`
+}
+
+func TestEnum_URLDecoder(t *testing.T) {
+	tests := []struct {
+		path   string
+		expect result[enumRequest]
+	}{
+		{
+			"/active?status=archived",
+			okResult(enumRequest{Status: "archived", Kind: ptrTo[itemStatus]("active")}),
+		},
+		{
+			"/active",
+			okResult(enumRequest{Kind: ptrTo[itemStatus]("active")}),
+		},
+		{
+			"/active?status=deleted",
+			result[enumRequest]{error: `400: invalid status "deleted" (expected one of active, archived)`},
+		},
+		{
+			"/bogus",
+			result[enumRequest]{error: `400: invalid kind "bogus" (expected one of active, archived)`},
+		},
+	}
+
+	for _, test := range tests {
+		var got enumRequest
+
+		r := chi.NewRouter()
+		r.Get("/{kind}", func(w http.ResponseWriter, r *http.Request) {
+			err := URLDecoder.Decode(r, &got)
+			if res := combineResult(got, err); res.error != test.expect.error ||
+				(err == nil && !reflect.DeepEqual(res.value, test.expect.value)) {
+				t.Errorf("%s: unexpected result:\n expected: %+v\n got: %+v", test.path, test.expect, res)
+			}
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", test.path, nil))
+	}
+}
+
+func TestEnum_JSON(t *testing.T) {
+	tests := []struct {
+		body   string
+		expect string
+	}{
+		{`{"filter": {"statuses": ["active", "archived"]}}`, ""},
+		{`{}`, ""},
+		{`{"filter": {"statuses": ["active", "nope"]}}`, `400: invalid filter.statuses[1] "nope" (expected one of active, archived)`},
+	}
+
+	for _, test := range tests {
+		var req enumBody
+		err := JSONEncoder.Decode(httptest.NewRequest("POST", "/", strings.NewReader(test.body)), &req)
+
+		if res := combineResult(req, err); res.error != test.expect {
+			t.Errorf("%s: unexpected error:\n expected: %s\n got: %s", test.body, test.expect, res.error)
+		}
+	}
+}
+
+func TestEnum_handler(t *testing.T) {
+	h := Wrap(func(ctx context.Context, req enumRequest) (None, error) {
+		return Empty, nil
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?status=deleted", nil))
+
+	expect := `{"error":"400: invalid status \"deleted\" (expected one of active, archived)"}`
+	if rec.Code != 400 || strings.TrimSpace(rec.Body.String()) != expect {
+		t.Errorf("unexpected body:\n expected: 400 %s\n got: %d %s", expect, rec.Code, rec.Body)
+	}
+}
+
+func TestValidateEnum(t *testing.T) {
+	if err := ValidateEnum(itemStatus("active")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var enumErr *EnumError
+	if err := ValidateEnum(itemStatus("gone")); !errors.As(err, &enumErr) || enumErr.Value != "gone" {
+		t.Errorf("expected EnumError, got %v", err)
+	}
+}
+
+func TestEnum_schema(t *testing.T) {
+	g := NewSchemaGenerator()
+	s := g.Generate(reflect.TypeFor[[]itemStatus]())
+	assertJSONEqual(t, "enum", `{"type":"array","items":{"type":"string","enum":["active","archived"]}}`, s)
+}
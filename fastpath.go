@@ -0,0 +1,374 @@
+package hrt
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+// The interfaces and functions in this file are fast paths that avoid most of
+// the reflection of decoding and encoding. They are usually implemented by
+// code generated by hrtgen:
+//
+//	//go:generate go run libdb.so/hrt/v2/cmd/hrtgen -type ListRequest,Item
+//
+// Generated code behaves the same as the reflection-based code that it
+// replaces, so it can be added or removed without changing the API.
+
+// URLDecodable is implemented by request types that decode themselves from URL
+// parameters and form values. URLDecoder calls DecodeURL instead of walking
+// the type using reflection.
+type URLDecodable interface {
+	DecodeURL(d *URLDecodeState) error
+}
+
+// JSONAppender is implemented by response types that encode themselves as
+// JSON. JSONEncoder calls AppendJSON instead of using encoding/json. The
+// appended JSON must be the same as what json.Marshal would produce.
+type JSONAppender interface {
+	AppendJSON(b []byte) ([]byte, error)
+}
+
+// EnumChecker is implemented by types that check the Enum values within them.
+// Decoders call CheckEnums instead of walking the value using reflection. The
+// returned error is wrapped in a 400 Bad Request error.
+type EnumChecker interface {
+	CheckEnums() error
+}
+
+// URLDecodeState is passed to URLDecodable.DecodeURL. It gives access to the
+// values of the request and to the options of the URLDecoder.
+type URLDecodeState struct {
+	r      *http.Request
+	rctx   *chi.Context
+	opts   URLDecoderOpts
+	folded map[string][]string
+}
+
+func newURLDecodeState(r *http.Request, opts URLDecoderOpts) *URLDecodeState {
+	return &URLDecodeState{
+		r:    r,
+		rctx: chi.RouteContext(r.Context()),
+		opts: opts,
+	}
+}
+
+// Policy returns the parsing policy of the decoder. Tag options are applied on
+// top of it.
+func (d *URLDecodeState) Policy() ParsePolicy {
+	return d.opts.Policy
+}
+
+// Form returns the form value with the given key and whether it is present.
+func (d *URLDecodeState) Form(key string) (string, bool) {
+	return formValue(d.r, key)
+}
+
+// Param returns the chi URL parameter with the given key and whether it is
+// present.
+func (d *URLDecodeState) Param(key string) (string, bool) {
+	return urlParamFrom(d.rctx, key)
+}
+
+// FormValues returns all form values with the given key and whether it is
+// present.
+func (d *URLDecodeState) FormValues(key string) ([]string, bool) {
+	return formValues(d.r, key)
+}
+
+// Named returns the URL parameter or otherwise the form value whose key
+// matches the given field name case-insensitively, as used for untagged
+// fields.
+func (d *URLDecodeState) Named(name string) (string, bool) {
+	vals, ok := d.NamedValues(name)
+	if !ok {
+		return "", false
+	}
+	return vals[0], true
+}
+
+// NamedValues is like Named, but returns all form values of the key.
+func (d *URLDecodeState) NamedValues(name string) ([]string, bool) {
+	if d.folded == nil {
+		d.folded = foldForm(d.r)
+	}
+	return namedValues(d.r, d.rctx, name, d.folded)
+}
+
+// JSON decodes the field with the given json tag key into dst, which must be a
+// pointer. A URL parameter is parsed like any other value, while a form value
+// is unmarshaled as JSON unless dst is a string or has a parser.
+func (d *URLDecodeState) JSON(key string, dst any, p ParsePolicy) error {
+	rv := reflect.ValueOf(dst).Elem()
+	f := &fieldPlan{typ: rv.Type(), source: sourceJSON, key: key, slice: isParsableSlice(rv.Type())}
+	return decodeJSONField(d.r, d.rctx, f, rv, d.parseOpts(p))
+}
+
+func (d *URLDecodeState) parseOpts(p ParsePolicy) rfutil.ParseOpts {
+	opts := p.parseOpts()
+	opts.Parsers = d.opts.Parsers
+	return opts
+}
+
+// hasParser returns true if the decoder has a parser for T, in which case the
+// typed parse functions defer to ParseURLValue.
+func hasParser[T any](d *URLDecodeState) bool {
+	if len(d.opts.Parsers) == 0 {
+		return false
+	}
+	_, ok := d.opts.Parsers[reflect.TypeFor[T]()]
+	return ok
+}
+
+// ParseURLValue parses val into dst, which must be a pointer, using
+// reflection. It supports every type that URLDecoder supports.
+func ParseURLValue(d *URLDecodeState, dst any, val string, present bool, p ParsePolicy) error {
+	rv := reflect.ValueOf(dst).Elem()
+	return rfutil.SetFromString(rv.Type(), rv, val, present, d.parseOpts(p))
+}
+
+// ParseURLValues parses vals into dst, which must be a pointer, using
+// reflection. Slices take all values, each of which may contain several
+// comma-separated elements, while other types only take the first value.
+func ParseURLValues(d *URLDecodeState, dst any, vals []string, present bool, p ParsePolicy) error {
+	rv := reflect.ValueOf(dst).Elem()
+	return setFromValues(rv.Type(), rv, isParsableSlice(rv.Type()), vals, present, d.parseOpts(p))
+}
+
+// needsParse handles absent and empty values the same way as
+// rfutil.SetFromString. It returns true if val still needs to be parsed.
+func needsParse[T any](dst *T, val *string, present, isBool bool, p ParsePolicy) bool {
+	if !present {
+		return false
+	}
+	if *val != "" {
+		return true
+	}
+	switch {
+	case isBool && p.Bool == BoolPresence:
+		*val = "true"
+		return true
+	case p.Empty == EmptyZero:
+		var zero T
+		*dst = zero
+	}
+	return false
+}
+
+// ParseURLString parses val into dst like ParseURLValue.
+func ParseURLString[T ~string](d *URLDecodeState, dst *T, val string, present bool, p ParsePolicy) error {
+	if hasParser[T](d) {
+		return ParseURLValue(d, dst, val, present, p)
+	}
+	if needsParse(dst, &val, present, false, p) {
+		*dst = T(val)
+	}
+	return nil
+}
+
+// ParseURLInt parses val into dst like ParseURLValue.
+func ParseURLInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](d *URLDecodeState, dst *T, val string, present bool, p ParsePolicy) error {
+	if hasParser[T](d) {
+		return ParseURLValue(d, dst, val, present, p)
+	}
+	if !needsParse(dst, &val, present, false, p) {
+		return nil
+	}
+	i, err := strconv.ParseInt(val, intBase(p), reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return errors.Wrap(err, "invalid int")
+	}
+	*dst = T(i)
+	return nil
+}
+
+// ParseURLUint parses val into dst like ParseURLValue.
+func ParseURLUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](d *URLDecodeState, dst *T, val string, present bool, p ParsePolicy) error {
+	if hasParser[T](d) {
+		return ParseURLValue(d, dst, val, present, p)
+	}
+	if !needsParse(dst, &val, present, false, p) {
+		return nil
+	}
+	i, err := strconv.ParseUint(val, intBase(p), reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return errors.Wrap(err, "invalid uint")
+	}
+	*dst = T(i)
+	return nil
+}
+
+// ParseURLFloat parses val into dst like ParseURLValue.
+func ParseURLFloat[T ~float32 | ~float64](d *URLDecodeState, dst *T, val string, present bool, p ParsePolicy) error {
+	if hasParser[T](d) {
+		return ParseURLValue(d, dst, val, present, p)
+	}
+	if !needsParse(dst, &val, present, false, p) {
+		return nil
+	}
+	f, err := strconv.ParseFloat(val, reflect.TypeFor[T]().Bits())
+	if err != nil {
+		return errors.Wrap(err, "invalid float")
+	}
+	*dst = T(f)
+	return nil
+}
+
+// ParseURLBool parses val into dst like ParseURLValue.
+func ParseURLBool[T ~bool](d *URLDecodeState, dst *T, val string, present bool, p ParsePolicy) error {
+	if hasParser[T](d) {
+		return ParseURLValue(d, dst, val, present, p)
+	}
+	if !needsParse(dst, &val, present, true, p) {
+		return nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return errors.Wrap(err, "invalid bool")
+	}
+	*dst = T(b)
+	return nil
+}
+
+func intBase(p ParsePolicy) int {
+	if p.IntPrefixes {
+		return 0
+	}
+	return 10
+}
+
+// CheckEnum returns an EnumError naming the given field if v is not one of its
+// allowed values. The zero value is always allowed.
+func CheckEnum[T interface {
+	comparable
+	Enum
+}](field string, v T) error {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return validateEnum(field, reflect.ValueOf(v))
+}
+
+// CheckEnumsOf checks the Enum values within v using reflection, naming
+// fields relative to the given field name. It is used for fields whose types
+// do not implement EnumChecker.
+func CheckEnumsOf(field string, v any) error {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil
+	}
+	return checkEnumValue(field, rv)
+}
+
+// AppendJSONValue appends the JSON encoding of v to b using encoding/json.
+func AppendJSONValue(b []byte, v any) ([]byte, error) {
+	if a, ok := v.(JSONAppender); ok {
+		return a.AppendJSON(b)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		return b, err
+	}
+	return append(b, j...), nil
+}
+
+// AppendJSONString appends s as a JSON string to b, escaping it the same way
+// as encoding/json.
+func AppendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			case '<', '>', '&':
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			default:
+				// Other control characters are escaped differently by
+				// different Go versions, so leave them to encoding/json.
+				b = appendMarshaledString(b, s[i:i+1])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// So are invalid bytes.
+			b = append(b, s[start:i]...)
+			b = appendMarshaledString(b, s[i:i+size])
+		case r == '\u2028' || r == '\u2029':
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendMarshaledString appends the contents of the JSON string that
+// encoding/json encodes s as, without the quotes.
+func appendMarshaledString(b []byte, s string) []byte {
+	j, _ := json.Marshal(s)
+	return append(b, j[1:len(j)-1]...)
+}
+
+// AppendJSONFloat appends f as a JSON number to b, formatting it the same way
+// as encoding/json. bits is 32 for float32 and 64 for float64.
+func AppendJSONFloat(b []byte, f float64, bits int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return b, &json.UnsupportedValueError{
+			Value: reflect.ValueOf(f),
+			Str:   strconv.FormatFloat(f, 'g', -1, bits),
+		}
+	}
+
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,201 @@
+package hrt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fieldTree is a tree of selected JSON fields. A field mapping to an empty
+// tree is selected as a whole.
+type fieldTree map[string]fieldTree
+
+// parseFieldTree parses a comma-separated list of dot-separated field paths,
+// e.g. "id,name,owner.email".
+func parseFieldTree(s string) fieldTree {
+	tree := fieldTree{}
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := tree
+		parts := strings.Split(path, ".")
+		for i, part := range parts {
+			child, ok := node[part]
+			if ok && len(child) == 0 {
+				// The field is already selected as a whole.
+				break
+			}
+			if i == len(parts)-1 {
+				// Selecting a field as a whole discards its selected children.
+				node[part] = fieldTree{}
+				break
+			}
+			if !ok {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// fieldSelection wraps a response value so that only the selected fields are
+// marshaled into JSON. The optional interfaces that writeResponse checks for
+// are forwarded to the wrapped value.
+type fieldSelection struct {
+	value    any
+	fields   fieldTree
+	selected string
+}
+
+func newFieldSelection(v any, fields string) fieldSelection {
+	tree := parseFieldTree(fields)
+	if _, ok := v.(paginated); ok {
+		// Apply the selection to the page's items instead of the page itself.
+		tree = fieldTree{
+			"items":       tree,
+			"next_cursor": {},
+			"prev_cursor": {},
+			"next_offset": {},
+		}
+	}
+	return fieldSelection{v, tree, fields}
+}
+
+// LastModified implements LastModifier. The zero time is returned if the
+// wrapped value does not implement LastModifier.
+func (s fieldSelection) LastModified() time.Time {
+	if lm, ok := s.value.(LastModifier); ok {
+		return lm.LastModified()
+	}
+	return time.Time{}
+}
+
+// ETag implements ETagger. The entity tag of the wrapped value is suffixed
+// with a hash of the selected fields, since every selection is a different
+// representation. An empty string is returned if the wrapped value does not
+// implement ETagger, in which case the ETag is computed from the body.
+func (s fieldSelection) ETag() string {
+	e, ok := s.value.(ETagger)
+	if !ok {
+		return ""
+	}
+	etag := quoteETag(e.ETag())
+	if etag == `""` {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.selected))
+	return strings.TrimSuffix(etag, `"`) + "-" + base64.RawURLEncoding.EncodeToString(sum[:6]) + `"`
+}
+
+var (
+	_ LastModifier = fieldSelection{}
+	_ ETagger      = fieldSelection{}
+)
+
+// Validate implements Validator by validating the wrapped value.
+func (s fieldSelection) Validate() error {
+	if v, ok := s.value.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s fieldSelection) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(s.value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := pruneJSON(&buf, b, s.fields); err != nil {
+		return nil, errors.Wrap(err, "failed to select fields")
+	}
+	return buf.Bytes(), nil
+}
+
+// pruneJSON writes the given JSON value into buf with only the fields in tree.
+// Arrays have the selection applied to each of their elements. The order of
+// object keys is preserved.
+func pruneJSON(buf *bytes.Buffer, b json.RawMessage, tree fieldTree) error {
+	b = bytes.TrimSpace(b)
+	if len(tree) == 0 || len(b) == 0 {
+		buf.Write(b)
+		return nil
+	}
+
+	switch b[0] {
+	case '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(b, &elems); err != nil {
+			return err
+		}
+
+		buf.WriteByte('[')
+		for i, elem := range elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := pruneJSON(buf, elem, tree); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(b))
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+		buf.WriteByte('{')
+		var n int
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := keyToken.(string)
+
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return err
+			}
+
+			subtree, ok := tree[key]
+			if !ok {
+				continue
+			}
+
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			n++
+
+			k, _ := json.Marshal(key)
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err := pruneJSON(buf, value, subtree); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	default:
+		// Scalars cannot be pruned.
+		buf.Write(b)
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fieldsOwner struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+type fieldsItem struct {
+	ID    int         `json:"id"`
+	Name  string      `json:"name"`
+	Owner fieldsOwner `json:"owner"`
+	Tags  []string    `json:"tags"`
+}
+
+func TestFieldSelection(t *testing.T) {
+	item := fieldsItem{
+		ID:    1,
+		Name:  "thing",
+		Owner: fieldsOwner{ID: 2, Email: "a@b.c"},
+		Tags:  []string{"x"},
+	}
+
+	opts := DefaultOpts
+	opts.FieldsParam = "fields"
+
+	r := NewRouter(opts)
+	r.Get("/item", Wrap(func(ctx context.Context, req None) (fieldsItem, error) {
+		return item, nil
+	}))
+	r.Get("/items", Wrap(func(ctx context.Context, req None) ([]fieldsItem, error) {
+		return []fieldsItem{item, item}, nil
+	}))
+	r.Get("/page", Wrap(func(ctx context.Context, req None) (Page[fieldsItem], error) {
+		return Page[fieldsItem]{Items: []fieldsItem{item}, NextCursor: "next"}, nil
+	}))
+
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{
+			path:   "/item",
+			expect: `{"id":1,"name":"thing","owner":{"id":2,"email":"a@b.c"},"tags":["x"]}`,
+		},
+		{
+			path:   "/item?fields=name,id",
+			expect: `{"id":1,"name":"thing"}`,
+		},
+		{
+			path:   "/item?fields=owner.email,tags,unknown",
+			expect: `{"owner":{"email":"a@b.c"},"tags":["x"]}`,
+		},
+		{
+			path:   "/item?fields=owner.email,owner",
+			expect: `{"owner":{"id":2,"email":"a@b.c"}}`,
+		},
+		{
+			path:   "/items?fields=id",
+			expect: `[{"id":1},{"id":1}]`,
+		},
+		{
+			path:   "/page?fields=id",
+			expect: `{"items":[{"id":1}],"next_cursor":"next"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+
+			if body := strings.TrimSpace(rec.Body.String()); body != test.expect {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.expect, body)
+			}
+		})
+	}
+}
+
+func TestFieldSelection_conditionalGET(t *testing.T) {
+	updated := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	opts := DefaultOpts
+	opts.FieldsParam = "fields"
+	opts.ConditionalGET = true
+
+	r := NewRouter(opts)
+	r.Get("/versioned", Wrap(func(ctx context.Context, req None) (versionedResponse, error) {
+		return versionedResponse{Name: "hi", Version: "v1", Updated: updated}, nil
+	}))
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/versioned?fields=name", nil)
+	if rec.Code != 200 {
+		t.Fatalf("unexpected status %d", rec.Code)
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm != updated.Format(http.TimeFormat) {
+		t.Errorf("unexpected Last-Modified %q", lm)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" || etag == `"v1"` {
+		t.Errorf("expected an ETag specific to the selection, got %q", etag)
+	}
+	if other := get("/versioned?fields=name,id", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("expected different selections to have different ETags, got %q", other)
+	}
+
+	rec = get("/versioned?fields=name", http.Header{"If-Modified-Since": {updated.Format(http.TimeFormat)}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for If-Modified-Since, got %d", rec.Code)
+	}
+
+	rec = get("/versioned?fields=name", http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for If-None-Match, got %d", rec.Code)
+	}
+}
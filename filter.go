@@ -0,0 +1,572 @@
+package hrt
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// FilterOp is a comparison operator in a filter expression.
+type FilterOp string
+
+// Supported filter operators.
+const (
+	FilterEq         FilterOp = "eq"
+	FilterNe         FilterOp = "ne"
+	FilterGt         FilterOp = "gt"
+	FilterGe         FilterOp = "ge"
+	FilterLt         FilterOp = "lt"
+	FilterLe         FilterOp = "le"
+	FilterContains   FilterOp = "contains"
+	FilterStartsWith FilterOp = "startswith"
+)
+
+// FilterExpr is a node in a filter expression tree. It is one of FilterAnd,
+// FilterOr, FilterNot or FilterCondition.
+type FilterExpr interface {
+	fmt.Stringer
+	filterExpr()
+}
+
+// FilterAnd matches if all of its expressions match.
+type FilterAnd []FilterExpr
+
+// FilterOr matches if any of its expressions match.
+type FilterOr []FilterExpr
+
+// FilterNot matches if its expression does not match.
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+// FilterCondition compares a field to a value. Value is a string, float64,
+// bool, time.Time or nil, depending on the literal and the field's type.
+type FilterCondition struct {
+	// Field is the JSON name of the field. Nested fields are separated with
+	// dots.
+	Field string
+	Op    FilterOp
+	Value any
+}
+
+func (FilterAnd) filterExpr()       {}
+func (FilterOr) filterExpr()        {}
+func (FilterNot) filterExpr()       {}
+func (FilterCondition) filterExpr() {}
+
+func (e FilterAnd) String() string { return joinFilterExprs(e, " and ") }
+func (e FilterOr) String() string  { return joinFilterExprs(e, " or ") }
+func (e FilterNot) String() string { return "not (" + e.Expr.String() + ")" }
+
+func (e FilterCondition) String() string {
+	var value string
+	switch v := e.Value.(type) {
+	case nil:
+		value = "null"
+	case string:
+		value = strconv.Quote(v)
+	case time.Time:
+		value = strconv.Quote(v.Format(time.RFC3339Nano))
+	default:
+		value = fmt.Sprint(v)
+	}
+	return e.Field + " " + string(e.Op) + " " + value
+}
+
+func joinFilterExprs(exprs []FilterExpr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = "(" + expr.String() + ")"
+	}
+	return strings.Join(parts, sep)
+}
+
+// Filter is a filter expression over the fields of T. It implements
+// encoding.TextUnmarshaler, so it can be decoded by URLDecoder like any other
+// field. Only fields of T that are marshaled into JSON may be filtered on, and
+// they are referred to by their JSON names.
+//
+// The syntax is:
+//
+//	expr       = or
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | condition
+//	condition  = field op value
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "contains" | "startswith"
+//	value      = string | number | "true" | "false" | "null"
+//
+// # Example
+//
+//	type ListUsersRequest struct {
+//	    Filter hrt.Filter[User] `query:"filter"`
+//	    Sort   hrt.Sort[User]   `query:"sort"`
+//	}
+//
+// A request to /users?filter=name eq "bob" and age gt 30 would then have
+// Filter.Expr set to a FilterAnd of two FilterConditions.
+type Filter[T any] struct {
+	// Expr is the parsed expression. It is nil if no filter was given.
+	Expr FilterExpr
+}
+
+var _ encoding.TextUnmarshaler = (*Filter[struct{}])(nil)
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *Filter[T]) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		f.Expr = nil
+		return nil
+	}
+
+	p := filterParser{
+		fields: queryFieldsOf(reflect.TypeFor[T]()),
+		lexer:  filterLexer{src: string(text)},
+	}
+
+	expr, err := p.parse()
+	if err != nil {
+		return errors.Wrap(err, "invalid filter")
+	}
+
+	f.Expr = expr
+	return nil
+}
+
+// String returns the filter expression.
+func (f Filter[T]) String() string {
+	if f.Expr == nil {
+		return ""
+	}
+	return f.Expr.String()
+}
+
+// SortField is a field to sort by.
+type SortField struct {
+	// Field is the JSON name of the field.
+	Field string
+	// Descending is true if the field should be sorted in descending order.
+	Descending bool
+}
+
+// Sort is a list of fields of T to sort by, in order of priority. It is
+// decoded from a comma-separated list of JSON field names, each optionally
+// prefixed with "-" for descending or "+" for ascending order, e.g.
+// "-created_at,name".
+type Sort[T any] []SortField
+
+var _ encoding.TextUnmarshaler = (*Sort[struct{}])(nil)
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Sort[T]) UnmarshalText(text []byte) error {
+	fields := queryFieldsOf(reflect.TypeFor[T]())
+	sort := Sort[T]{}
+
+	for _, part := range strings.Split(string(text), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var field SortField
+		switch part[0] {
+		case '-':
+			field.Descending = true
+			part = part[1:]
+		case '+':
+			part = part[1:]
+		}
+		field.Field = part
+
+		kind, ok := fields[part]
+		if !ok {
+			return fmt.Errorf("cannot sort by unknown field %q", part)
+		}
+		if kind == queryFieldBool {
+			return fmt.Errorf("cannot sort by boolean field %q", part)
+		}
+
+		sort = append(sort, field)
+	}
+
+	*s = sort
+	return nil
+}
+
+type queryFieldKind uint8
+
+const (
+	queryFieldString queryFieldKind = iota
+	queryFieldNumber
+	queryFieldBool
+	queryFieldTime
+)
+
+var (
+	timeType         = reflect.TypeFor[time.Time]()
+	queryFieldsCache sync.Map // map[reflect.Type]map[string]queryFieldKind
+)
+
+// queryFieldsOf returns the filterable fields of the given type, keyed by
+// their dot-separated JSON names.
+func queryFieldsOf(rt reflect.Type) map[string]queryFieldKind {
+	if fields, ok := queryFieldsCache.Load(rt); ok {
+		return fields.(map[string]queryFieldKind)
+	}
+
+	fields := make(map[string]queryFieldKind)
+	collectQueryFields(fields, rt, "", 0)
+
+	queryFieldsCache.Store(rt, fields)
+	return fields
+}
+
+func collectQueryFields(fields map[string]queryFieldKind, rt reflect.Type, prefix string, depth int) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt.Kind() != reflect.Struct || depth > 8 {
+		return
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		rft := rt.Field(i)
+
+		name, _, _ := strings.Cut(rft.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if rft.Anonymous && name == "" {
+			collectQueryFields(fields, rft.Type, prefix, depth+1)
+			continue
+		}
+
+		if !rft.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = rft.Name
+		}
+
+		ft := rft.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		switch {
+		case ft == timeType:
+			fields[prefix+name] = queryFieldTime
+		case ft.Kind() == reflect.String:
+			fields[prefix+name] = queryFieldString
+		case ft.Kind() == reflect.Bool:
+			fields[prefix+name] = queryFieldBool
+		case ft.Kind() >= reflect.Int && ft.Kind() <= reflect.Float64:
+			fields[prefix+name] = queryFieldNumber
+		case ft.Kind() == reflect.Struct:
+			collectQueryFields(fields, ft, prefix+name+".", depth+1)
+		}
+	}
+}
+
+type filterTokenKind uint8
+
+const (
+	filterTokenEOF filterTokenKind = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenLParen
+	filterTokenRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+type filterLexer struct {
+	src string
+	pos int
+}
+
+func (l *filterLexer) next() (filterToken, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return filterToken{kind: filterTokenEOF, pos: start}, nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '(':
+		l.pos++
+		return filterToken{filterTokenLParen, "(", start}, nil
+
+	case c == ')':
+		l.pos++
+		return filterToken{filterTokenRParen, ")", start}, nil
+
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return filterToken{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return filterToken{}, fmt.Errorf("invalid string at %d", start)
+		}
+		return filterToken{filterTokenString, s, start}, nil
+
+	case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		for l.pos < len(l.src) && strings.IndexByte("+-.0123456789eE", l.src[l.pos]) != -1 {
+			l.pos++
+		}
+		return filterToken{filterTokenNumber, l.src[start:l.pos], start}, nil
+
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && isFilterIdentByte(l.src[l.pos]) {
+			l.pos++
+		}
+		return filterToken{filterTokenIdent, l.src[start:l.pos], start}, nil
+
+	default:
+		return filterToken{}, fmt.Errorf("unexpected character %q at %d", c, start)
+	}
+}
+
+func isFilterIdentByte(c byte) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+type filterParser struct {
+	fields map[string]queryFieldKind
+	lexer  filterLexer
+	tok    filterToken
+	peeked bool
+}
+
+func (p *filterParser) peek() (filterToken, error) {
+	if !p.peeked {
+		tok, err := p.lexer.next()
+		if err != nil {
+			return tok, err
+		}
+		p.tok = tok
+		p.peeked = true
+	}
+	return p.tok, nil
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	tok, err := p.peek()
+	p.peeked = false
+	return tok, err
+}
+
+func (p *filterParser) parse() (FilterExpr, error) {
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != filterTokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+
+	return expr, nil
+}
+
+func (p *filterParser) parseOr() (FilterExpr, error) {
+	return p.parseLogical("or", p.parseAnd, func(exprs []FilterExpr) FilterExpr { return FilterOr(exprs) })
+}
+
+func (p *filterParser) parseAnd() (FilterExpr, error) {
+	return p.parseLogical("and", p.parseUnary, func(exprs []FilterExpr) FilterExpr { return FilterAnd(exprs) })
+}
+
+func (p *filterParser) parseLogical(keyword string, operand func() (FilterExpr, error), combine func([]FilterExpr) FilterExpr) (FilterExpr, error) {
+	expr, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := []FilterExpr{expr}
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != filterTokenIdent || !strings.EqualFold(tok.text, keyword) {
+			break
+		}
+		p.next()
+
+		expr, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return combine(exprs), nil
+}
+
+func (p *filterParser) parseUnary() (FilterExpr, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok.kind == filterTokenIdent && strings.EqualFold(tok.text, "not"):
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{expr}, nil
+
+	case tok.kind == filterTokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != filterTokenRParen {
+			return nil, fmt.Errorf("expected ) at %d", tok.pos)
+		}
+		return expr, nil
+
+	case tok.kind == filterTokenIdent:
+		return p.parseCondition(tok)
+
+	case tok.kind == filterTokenEOF:
+		return nil, errors.New("unexpected end of filter")
+
+	default:
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+}
+
+func (p *filterParser) parseCondition(field filterToken) (FilterExpr, error) {
+	kind, ok := p.fields[field.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field.text)
+	}
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	op := FilterOp(strings.ToLower(opTok.text))
+	switch op {
+	case FilterEq, FilterNe:
+	case FilterGt, FilterGe, FilterLt, FilterLe:
+		if kind == queryFieldBool {
+			return nil, fmt.Errorf("operator %q is not supported on boolean field %q", op, field.text)
+		}
+	case FilterContains, FilterStartsWith:
+		if kind != queryFieldString {
+			return nil, fmt.Errorf("operator %q is only supported on string fields", op)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q at %d", opTok.text, opTok.pos)
+	}
+
+	valueTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := parseFilterValue(valueTok, kind)
+	if err != nil {
+		return nil, errors.Wrapf(err, "field %q", field.text)
+	}
+	if value == nil && op != FilterEq && op != FilterNe {
+		return nil, fmt.Errorf("operator %q cannot be used with null", op)
+	}
+
+	return FilterCondition{
+		Field: field.text,
+		Op:    op,
+		Value: value,
+	}, nil
+}
+
+func parseFilterValue(tok filterToken, kind queryFieldKind) (any, error) {
+	if tok.kind == filterTokenIdent && tok.text == "null" {
+		return nil, nil
+	}
+
+	switch kind {
+	case queryFieldString:
+		if tok.kind != filterTokenString {
+			return nil, fmt.Errorf("expected string at %d", tok.pos)
+		}
+		return tok.text, nil
+
+	case queryFieldTime:
+		if tok.kind != filterTokenString {
+			return nil, fmt.Errorf("expected RFC 3339 time string at %d", tok.pos)
+		}
+		t, err := time.Parse(time.RFC3339Nano, tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time at %d", tok.pos)
+		}
+		return t, nil
+
+	case queryFieldNumber:
+		if tok.kind != filterTokenNumber {
+			return nil, fmt.Errorf("expected number at %d", tok.pos)
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at %d", tok.pos)
+		}
+		return f, nil
+
+	case queryFieldBool:
+		if tok.kind == filterTokenIdent {
+			switch tok.text {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("expected true or false at %d", tok.pos)
+
+	default:
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+}
@@ -0,0 +1,122 @@
+package hrt
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type filterItem struct {
+	Name      string    `json:"name"`
+	Age       int       `json:"age"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+	Owner     struct {
+		Email *string `json:"email"`
+	} `json:"owner"`
+	Secret string `json:"-"`
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect result[string]
+	}{
+		{
+			input:  `name eq "bob"`,
+			expect: okResult(`name eq "bob"`),
+		},
+		{
+			input:  `name eq "bob" and age gt 30 or admin eq true`,
+			expect: okResult(`((name eq "bob") and (age gt 30)) or (admin eq true)`),
+		},
+		{
+			input:  `name startswith "b" and (age lt 10 or not age ge 20)`,
+			expect: okResult(`(name startswith "b") and ((age lt 10) or (not (age ge 20)))`),
+		},
+		{
+			input:  `owner.email ne null and created_at ge "2021-01-01T00:00:00Z"`,
+			expect: okResult(`(owner.email ne null) and (created_at ge "2021-01-01T00:00:00Z")`),
+		},
+		{
+			input:  `secret eq "x"`,
+			expect: result[string]{error: `invalid filter: unknown field "secret"`},
+		},
+		{
+			input:  `age eq "x"`,
+			expect: result[string]{error: `invalid filter: field "age": expected number at 7`},
+		},
+		{
+			input:  `admin gt true`,
+			expect: result[string]{error: `invalid filter: operator "gt" is not supported on boolean field "admin"`},
+		},
+		{
+			input:  `age contains 1`,
+			expect: result[string]{error: `invalid filter: operator "contains" is only supported on string fields`},
+		},
+		{
+			input:  `(name eq "bob"`,
+			expect: result[string]{error: `invalid filter: expected ) at 14`},
+		},
+		{
+			input:  `name eq "bob" age`,
+			expect: result[string]{error: `invalid filter: unexpected "age" at 14`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			var f Filter[filterItem]
+			err := f.UnmarshalText([]byte(test.input))
+			res := combineResult(f.String(), err)
+			if err != nil {
+				res.value = ""
+			}
+
+			if !reflect.DeepEqual(test.expect, res) {
+				t.Errorf("unexpected test result:\n"+
+					"expected: %v\n"+
+					"got:      %v\n", test.expect, res)
+			}
+		})
+	}
+}
+
+func TestFilter_urlDecoder(t *testing.T) {
+	type listRequest struct {
+		Filter Filter[filterItem] `query:"filter"`
+		Sort   Sort[filterItem]   `query:"sort"`
+	}
+
+	query := url.Values{
+		"filter": {`name eq "bob" and age gt 30`},
+		"sort":   {"-created_at,name"},
+	}
+
+	var got listRequest
+	if err := URLDecoder.Decode(httptest.NewRequest("GET", "/?"+query.Encode(), nil), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := listRequest{
+		Filter: Filter[filterItem]{FilterAnd{
+			FilterCondition{Field: "name", Op: FilterEq, Value: "bob"},
+			FilterCondition{Field: "age", Op: FilterGt, Value: 30.0},
+		}},
+		Sort: Sort[filterItem]{
+			{Field: "created_at", Descending: true},
+			{Field: "name"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+
+	var s Sort[filterItem]
+	if err := s.UnmarshalText([]byte("admin")); err == nil {
+		t.Error("expected error sorting by boolean field")
+	}
+}
@@ -13,6 +13,9 @@ type ctxKey uint8
 const (
 	routerOptsCtxKey ctxKey = iota
 	requestCtxKey
+	jwtClaimsCtxKey
+	principalCtxKey
+	batchCtxKey
 )
 
 // RequestFromContext returns the request from the Handler's context.
@@ -24,6 +27,35 @@ func RequestFromContext(ctx context.Context) *http.Request {
 type Opts struct {
 	Encoder     Encoder
 	ErrorWriter ErrorWriter
+
+	// ConditionalGET enables ETag and Last-Modified handling for GET and HEAD
+	// requests. The response is buffered so that an ETag can be computed from
+	// the encoded body, unless the response implements ETagger. Requests with
+	// a matching If-None-Match or If-Modified-Since header receive a 304 Not
+	// Modified response without a body.
+	ConditionalGET bool
+
+	// BufferSize is the maximum number of bytes of an encoded response that
+	// are buffered. If encoding fails within this many bytes, the partial
+	// response is discarded and a clean error is written instead. Larger
+	// responses are streamed. If zero, DefaultBufferSize is used; if negative,
+	// responses are never buffered.
+	BufferSize int
+
+	// Compression, if not nil, enables compressing responses using gzip or
+	// deflate as negotiated by Accept-Encoding, as well as transparently
+	// decompressing request bodies according to their Content-Encoding.
+	// Compression requires buffering, so it has no effect on responses if
+	// BufferSize is negative.
+	Compression *Compression
+
+	// FieldsParam, if not empty, is the name of the query parameter that
+	// selects which fields of the response are encoded, e.g.
+	// ?fields=id,name,owner.email. Fields are referred to by their JSON names,
+	// and nested fields are separated with dots. Arrays have the selection
+	// applied to each element, and Page responses have it applied to each
+	// item. The response must be encodable as JSON.
+	FieldsParam string
 }
 
 // DefaultOpts is the default options for the router.
@@ -70,66 +102,179 @@ type Handler[RequestT, ResponseT any] func(ctx context.Context, req RequestT) (R
 
 // Wrap wraps a handler into a http.Handler. It exists because Go's type
 // inference doesn't work well with the Handler type.
+//
+// Request fields may have a default value given by a `default` tag. Defaults
+// are set before the request is decoded, so they apply to every field that is
+// absent from the request regardless of the decoder, and they are seen by
+// Validate. For example:
+//
+//	type ListRequest struct {
+//	    Limit   int           `query:"limit" default:"20"`
+//	    Sort    []string      `query:"sort" default:"name,id"`
+//	    Timeout time.Duration `query:"timeout" default:"30s"`
+//	}
+//
+// Defaults are parsed like URL parameters. Slices are given as comma-separated
+// elements, time.Duration values use time.ParseDuration, and types
+// implementing encoding.TextUnmarshaler are supported. Fields of nested
+// structs may have defaults as well. A request that gives a value replaces the
+// default entirely, so ?sort=created results in a Sort of []string{"created"}.
+//
+// Wrap panics if the tags of RequestT are invalid, such as an unknown tag
+// option or a default that cannot be parsed, so that such mistakes surface at
+// startup rather than when the handler is first called.
 func Wrap[RequestT, ResponseT any](f func(ctx context.Context, req RequestT) (ResponseT, error)) http.Handler {
+	mustValidateRequestType(reflect.TypeFor[RequestT]())
 	return Handler[RequestT, ResponseT](f)
 }
 
 // ServeHTTP implements the http.Handler interface.
 func (h Handler[RequestT, ResponseT]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var req RequestT
+	serve(w, r, func(ctx context.Context, r *http.Request, opts Opts) (any, error) {
+		req, err := decodeRequest[RequestT](httpRequestDecoder(r, opts))
+		if err != nil {
+			return nil, WrapHTTPError(http.StatusBadRequest, err)
+		}
+		return h(ctx, req)
+	})
+}
 
+// serve implements the common parts of serving a typed handler. call decodes
+// the request and calls the handler.
+func serve(w http.ResponseWriter, r *http.Request, call func(context.Context, *http.Request, Opts) (any, error)) {
 	// Context cycle! Let's go!!
 	ctx := context.WithValue(r.Context(), requestCtxKey, r)
 
 	opts := OptsFromContext(ctx)
 
-	req, err := decodeRequest[RequestT](r, opts)
-	if err != nil {
-		opts.ErrorWriter.WriteError(w, WrapHTTPError(http.StatusBadRequest, err))
-		return
+	if opts.Compression != nil {
+		dr, err := decompressRequest(r, opts.Compression)
+		if err != nil {
+			opts.ErrorWriter.WriteError(w, err)
+			return
+		}
+		r = dr
+		ctx = context.WithValue(ctx, requestCtxKey, r)
 	}
 
-	resp, err := h(ctx, req)
+	resp, err := call(ctx, r, opts)
 	if err != nil {
+		setPreconditionHeaders(w, err)
 		opts.ErrorWriter.WriteError(w, err)
 		return
 	}
 
-	if _, ok := any(resp).(None); !ok {
+	writeResponse(w, r, opts, resp)
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, opts Opts, resp any) {
+	if e, ok := resp.(responseEnvelope); ok {
+		w, resp, ok = unwrapResponse(w, e)
+		if !ok {
+			return
+		}
+	}
+
+	if _, ok := resp.(None); ok {
+		return
+	}
+
+	if p, ok := resp.(paginated); ok {
+		setPageLinks(w, r, p)
+	}
+
+	if opts.FieldsParam != "" {
+		if fields := r.URL.Query().Get(opts.FieldsParam); fields != "" {
+			resp = newFieldSelection(resp, fields)
+		}
+	}
+
+	if opts.ConditionalGET && (r.Method == "GET" || r.Method == "HEAD") {
+		writeConditionalResponse(w, r, opts, resp)
+		return
+	}
+
+	if opts.BufferSize < 0 {
 		if err := opts.Encoder.Encode(w, resp); err != nil {
 			opts.ErrorWriter.WriteError(w, WrapHTTPError(http.StatusInternalServerError, err))
-			return
 		}
+		return
+	}
+
+	buf := newResponseBuffer(w, opts.bufferSize())
+	buf.Compress(r, opts.Compression)
+	defer buf.Release()
+
+	if err := opts.Encoder.Encode(buf, resp); err != nil {
+		// Only write the error if nothing has been sent yet. Otherwise, the
+		// client already has a partial response that we can't take back.
+		if !buf.Streaming() {
+			opts.ErrorWriter.WriteError(w, WrapHTTPError(http.StatusInternalServerError, err))
+		}
+		return
+	}
+
+	buf.Flush()
+}
+
+func (o Opts) bufferSize() int {
+	if o.BufferSize == 0 {
+		return DefaultBufferSize
 	}
+	return o.BufferSize
 }
 
-func decodeRequest[RequestT any](r *http.Request, opts Opts) (RequestT, error) {
+// decodeRequest decodes a request of type RequestT using decode. See
+// decodeRequestValue.
+func decodeRequest[RequestT any](decode func(v any) error) (RequestT, error) {
 	var req RequestT
-	if _, ok := any(req).(None); ok {
-		return req, nil
+
+	v, err := decodeRequestValue(reflect.TypeFor[RequestT](), decode)
+	if err != nil || !v.IsValid() {
+		return req, err
 	}
 
-	if reflect.TypeFor[RequestT]().Kind() == reflect.Ptr {
-		// RequestT is a pointer type, so we need to allocate a new instance.
-		v := reflect.New(reflect.TypeFor[RequestT]().Elem()).Interface()
+	reflect.ValueOf(&req).Elem().Set(v)
+	return req, nil
+}
 
-		if err := opts.Encoder.Decode(r, v); err != nil {
-			return req, err
-		}
+var noneType = reflect.TypeFor[None]()
 
-		// Return the value as-is, since it's already a pointer.
-		return v.(RequestT), nil
+// decodeRequestValue decodes a request of the given type. A new value is
+// allocated, its defaults are set and decode is called with a pointer to it.
+// If rt is a pointer type, then that pointer is returned. An invalid value is
+// returned if the type is None, in which case decode is not called.
+func decodeRequestValue(rt reflect.Type, decode func(v any) error) (reflect.Value, error) {
+	if rt == noneType {
+		return reflect.Value{}, nil
 	}
 
-	// RequestT is a value type, so we need to allocate a new pointer instance
-	// and dereference it afterwards.
-	v := reflect.New(reflect.TypeFor[RequestT]()).Interface()
+	t := rt
+	if rt.Kind() == reflect.Ptr {
+		t = rt.Elem()
+	}
 
-	if err := opts.Encoder.Decode(r, v); err != nil {
-		return req, err
+	v := reflect.New(t)
+	if err := setDefaults(v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if err := decode(v.Interface()); err != nil {
+		return reflect.Value{}, err
 	}
 
-	return *v.(*RequestT), nil
+	if rt.Kind() == reflect.Ptr {
+		return v, nil
+	}
+	return v.Elem(), nil
+}
+
+// httpRequestDecoder returns a function for decodeRequestValue that decodes
+// the HTTP request r using the Encoder in opts.
+func httpRequestDecoder(r *http.Request, opts Opts) func(v any) error {
+	return func(v any) error {
+		setIfMatchFields(r, v)
+		return opts.Encoder.Decode(r, v)
+	}
 }
 
 // HandlerIntrospection is a struct that contains information about a handler.
@@ -144,17 +289,24 @@ type HandlerIntrospection struct {
 }
 
 // TryIntrospectingHandler checks if h is an hrt.Handler and returns its
-// introspection if it is, otherwise it returns false.
+// introspection if it is, otherwise it returns false. Handlers wrapped by a
+// RouteAnnotator are unwrapped.
 func TryIntrospectingHandler(h http.Handler) (HandlerIntrospection, bool) {
 	type introspector interface {
 		Introspect() HandlerIntrospection
 	}
 	var _ introspector = Handler[None, None](nil)
 
-	if h, ok := h.(introspector); ok {
-		return h.Introspect(), true
+	for {
+		if h, ok := h.(introspector); ok {
+			return h.Introspect(), true
+		}
+		a, ok := h.(RouteAnnotator)
+		if !ok {
+			return HandlerIntrospection{}, false
+		}
+		h = a.Unwrap()
 	}
-	return HandlerIntrospection{}, false
 }
 
 // Introspect returns information about the handler.
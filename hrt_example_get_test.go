@@ -34,8 +34,7 @@ func handleEcho(ctx context.Context, req EchoRequest) (EchoResponse, error) {
 }
 
 func Example_get() {
-	r := hrt.NewRouter()
-	r.Use(hrt.Use(hrt.DefaultOpts))
+	r := hrt.NewRouter(hrt.DefaultOpts)
 	r.Get("/echo", hrt.Wrap(handleEcho))
 
 	srv := ht.NewServer(r)
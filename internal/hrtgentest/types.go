@@ -0,0 +1,87 @@
+// Package hrtgentest contains types with methods generated by hrtgen. It is
+// used to test that the generated code behaves like the reflection-based code
+// that it replaces.
+package hrtgentest
+
+import (
+	"time"
+
+	"libdb.so/hrt/v2"
+)
+
+//go:generate go run libdb.so/hrt/v2/cmd/hrtgen -type ListRequest,Item,Owner -output types_hrt.go
+
+// Status is an Enum.
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusArchived Status = "archived"
+)
+
+// Enum implements hrt.Enum.
+func (Status) Enum() []string {
+	return []string{string(StatusActive), string(StatusArchived)}
+}
+
+// Code is parsed using its UnmarshalText method.
+type Code int
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Code) UnmarshalText(b []byte) error {
+	*c = Code(len(b))
+	return nil
+}
+
+// ListRequest is a request decoded from URL parameters.
+type ListRequest struct {
+	ID       int           `url:"id"`
+	Limit    int           `query:"limit"`
+	Offset   uint32        `query:"offset,int=prefixed"`
+	Verbose  bool          `query:"verbose,bool=presence"`
+	Ratio    float64       `query:"ratio"`
+	Name     string        `query:"name,empty=zero"`
+	Status   Status        `query:"status"`
+	Statuses []Status      `json:"statuses"`
+	Since    time.Time     `query:"since,layout=date"`
+	Timeout  time.Duration `query:"timeout"`
+	Parent   *int          `query:"parent"`
+	Code     Code          `form:"code"`
+	Owner    string        `json:"owner"`
+	Tags     []string      `json:"tags"`
+	Filters  []Status      `query:"filter"`
+	Sizes    []int         `query:"size"`
+	Sort     string
+	IfMatch  hrt.IfMatch
+	Ignored  string `json:"-"`
+	internal string
+}
+
+// Item is a response encoded as JSON.
+type Item struct {
+	ID       int64          `json:"id"`
+	Name     string         `json:"name"`
+	Price    float32        `json:"price,omitempty"`
+	Score    float64        `json:"score"`
+	Active   bool           `json:"active,omitempty"`
+	Status   Status         `json:"status,omitempty"`
+	Owner    *Owner         `json:"owner"`
+	Owners   []Owner        `json:"owners,omitempty"`
+	Tags     []string       `json:"tags"`
+	Matrix   [][]int        `json:"matrix,omitempty"`
+	Data     []byte         `json:"data,omitempty"`
+	Created  time.Time      `json:"created"`
+	Meta     map[string]any `json:"meta,omitempty"`
+	Extra    any            `json:"extra"`
+	Note     *string        `json:"note,omitempty"`
+	Hidden   string         `json:"-"`
+	HTML     string         `json:"<html>"`
+	Untagged uint16
+	internal string
+}
+
+// Owner is nested within Item.
+type Owner struct {
+	Name   string  `json:"name"`
+	Status *Status `json:"status"`
+}
@@ -0,0 +1,573 @@
+// Code generated by hrtgen; DO NOT EDIT.
+
+package hrtgentest
+
+import (
+	"strconv"
+
+	hrt "libdb.so/hrt/v2"
+)
+
+// DecodeURL implements hrt.URLDecodable.
+func (v *ListRequest) DecodeURL(d *hrt.URLDecodeState) error {
+	p := d.Policy()
+	{
+		val, ok := d.Param("id")
+		if err := hrt.ParseURLInt(d, &v.ID, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("limit")
+		if err := hrt.ParseURLInt(d, &v.Limit, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		p := p
+		p.IntPrefixes = true
+		val, ok := d.Form("offset")
+		if err := hrt.ParseURLUint(d, &v.Offset, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		p := p
+		p.Bool = hrt.BoolPresence
+		val, ok := d.Form("verbose")
+		if err := hrt.ParseURLBool(d, &v.Verbose, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("ratio")
+		if err := hrt.ParseURLFloat(d, &v.Ratio, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		p := p
+		p.Empty = hrt.EmptyZero
+		val, ok := d.Form("name")
+		if err := hrt.ParseURLString(d, &v.Name, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("status")
+		if err := hrt.ParseURLString(d, &v.Status, val, ok, p); err != nil {
+			return err
+		}
+	}
+	if err := d.JSON("statuses", &v.Statuses, p); err != nil {
+		return err
+	}
+	{
+		p := p
+		p.TimeLayout = "2006-01-02"
+		val, ok := d.Form("since")
+		if err := hrt.ParseURLValue(d, &v.Since, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("timeout")
+		if err := hrt.ParseURLValue(d, &v.Timeout, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("parent")
+		if err := hrt.ParseURLValue(d, &v.Parent, val, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Form("code")
+		if err := hrt.ParseURLValue(d, &v.Code, val, ok, p); err != nil {
+			return err
+		}
+	}
+	if err := d.JSON("owner", &v.Owner, p); err != nil {
+		return err
+	}
+	if err := d.JSON("tags", &v.Tags, p); err != nil {
+		return err
+	}
+	{
+		vals, ok := d.FormValues("filter")
+		if err := hrt.ParseURLValues(d, &v.Filters, vals, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		vals, ok := d.FormValues("size")
+		if err := hrt.ParseURLValues(d, &v.Sizes, vals, ok, p); err != nil {
+			return err
+		}
+	}
+	{
+		val, ok := d.Named("Sort")
+		if err := hrt.ParseURLString(d, &v.Sort, val, ok, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendJSON implements hrt.JSONAppender.
+func (v ListRequest) AppendJSON(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, '{')
+	n := len(b)
+	b = append(b, "\"ID\":"...)
+	b = strconv.AppendInt(b, int64(v.ID), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Limit\":"...)
+	b = strconv.AppendInt(b, int64(v.Limit), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Offset\":"...)
+	b = strconv.AppendUint(b, uint64(v.Offset), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Verbose\":"...)
+	b = strconv.AppendBool(b, bool(v.Verbose))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Ratio\":"...)
+	if b, err = hrt.AppendJSONFloat(b, float64(v.Ratio), 64); err != nil {
+		return b, err
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Name\":"...)
+	b = hrt.AppendJSONString(b, string(v.Name))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Status\":"...)
+	b = hrt.AppendJSONString(b, string(v.Status))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"statuses\":"...)
+	if v.Statuses == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.Statuses {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = hrt.AppendJSONString(b, string(e0))
+		}
+		b = append(b, ']')
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Since\":"...)
+	if b, err = hrt.AppendJSONValue(b, v.Since); err != nil {
+		return b, err
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Timeout\":"...)
+	b = strconv.AppendInt(b, int64(v.Timeout), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Parent\":"...)
+	if v.Parent == nil {
+		b = append(b, "null"...)
+	} else {
+		b = strconv.AppendInt(b, int64(*v.Parent), 10)
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Code\":"...)
+	b = strconv.AppendInt(b, int64(v.Code), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"owner\":"...)
+	b = hrt.AppendJSONString(b, string(v.Owner))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"tags\":"...)
+	if v.Tags == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.Tags {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = hrt.AppendJSONString(b, string(e0))
+		}
+		b = append(b, ']')
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Filters\":"...)
+	if v.Filters == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.Filters {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = hrt.AppendJSONString(b, string(e0))
+		}
+		b = append(b, ']')
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Sizes\":"...)
+	if v.Sizes == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.Sizes {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendInt(b, int64(e0), 10)
+		}
+		b = append(b, ']')
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Sort\":"...)
+	b = hrt.AppendJSONString(b, string(v.Sort))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"IfMatch\":"...)
+	if v.IfMatch == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.IfMatch {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = hrt.AppendJSONString(b, string(e0))
+		}
+		b = append(b, ']')
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+// CheckEnums implements hrt.EnumChecker.
+func (v ListRequest) CheckEnums() error {
+	if err := hrt.CheckEnum("status", v.Status); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("statuses", v.Statuses); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("filter", v.Filters); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DecodeURL implements hrt.URLDecodable.
+func (v *Item) DecodeURL(d *hrt.URLDecodeState) error {
+	p := d.Policy()
+	if err := d.JSON("id", &v.ID, p); err != nil {
+		return err
+	}
+	if err := d.JSON("name", &v.Name, p); err != nil {
+		return err
+	}
+	if err := d.JSON("price", &v.Price, p); err != nil {
+		return err
+	}
+	if err := d.JSON("score", &v.Score, p); err != nil {
+		return err
+	}
+	if err := d.JSON("active", &v.Active, p); err != nil {
+		return err
+	}
+	if err := d.JSON("status", &v.Status, p); err != nil {
+		return err
+	}
+	if err := d.JSON("owner", &v.Owner, p); err != nil {
+		return err
+	}
+	if err := d.JSON("owners", &v.Owners, p); err != nil {
+		return err
+	}
+	if err := d.JSON("tags", &v.Tags, p); err != nil {
+		return err
+	}
+	if err := d.JSON("matrix", &v.Matrix, p); err != nil {
+		return err
+	}
+	if err := d.JSON("data", &v.Data, p); err != nil {
+		return err
+	}
+	if err := d.JSON("created", &v.Created, p); err != nil {
+		return err
+	}
+	if err := d.JSON("meta", &v.Meta, p); err != nil {
+		return err
+	}
+	if err := d.JSON("extra", &v.Extra, p); err != nil {
+		return err
+	}
+	if err := d.JSON("note", &v.Note, p); err != nil {
+		return err
+	}
+	if err := d.JSON("<html>", &v.HTML, p); err != nil {
+		return err
+	}
+	{
+		val, ok := d.Named("Untagged")
+		if err := hrt.ParseURLUint(d, &v.Untagged, val, ok, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendJSON implements hrt.JSONAppender.
+func (v Item) AppendJSON(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, '{')
+	n := len(b)
+	b = append(b, "\"id\":"...)
+	b = strconv.AppendInt(b, int64(v.ID), 10)
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"name\":"...)
+	b = hrt.AppendJSONString(b, string(v.Name))
+	if v.Price != 0 {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"price\":"...)
+		if b, err = hrt.AppendJSONFloat(b, float64(v.Price), 32); err != nil {
+			return b, err
+		}
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"score\":"...)
+	if b, err = hrt.AppendJSONFloat(b, float64(v.Score), 64); err != nil {
+		return b, err
+	}
+	if v.Active {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"active\":"...)
+		b = strconv.AppendBool(b, bool(v.Active))
+	}
+	if v.Status != "" {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"status\":"...)
+		b = hrt.AppendJSONString(b, string(v.Status))
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"owner\":"...)
+	if v.Owner == nil {
+		b = append(b, "null"...)
+	} else {
+		if b, err = v.Owner.AppendJSON(b); err != nil {
+			return b, err
+		}
+	}
+	if len(v.Owners) != 0 {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"owners\":"...)
+		b = append(b, '[')
+		for i, e0 := range v.Owners {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = e0.AppendJSON(b); err != nil {
+				return b, err
+			}
+		}
+		b = append(b, ']')
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"tags\":"...)
+	if v.Tags == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e0 := range v.Tags {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = hrt.AppendJSONString(b, string(e0))
+		}
+		b = append(b, ']')
+	}
+	if len(v.Matrix) != 0 {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"matrix\":"...)
+		b = append(b, '[')
+		for i, e0 := range v.Matrix {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if e0 == nil {
+				b = append(b, "null"...)
+			} else {
+				b = append(b, '[')
+				for i, e1 := range e0 {
+					if i > 0 {
+						b = append(b, ',')
+					}
+					b = strconv.AppendInt(b, int64(e1), 10)
+				}
+				b = append(b, ']')
+			}
+		}
+		b = append(b, ']')
+	}
+	if len(v.Data) != 0 {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"data\":"...)
+		if b, err = hrt.AppendJSONValue(b, v.Data); err != nil {
+			return b, err
+		}
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"created\":"...)
+	if b, err = hrt.AppendJSONValue(b, v.Created); err != nil {
+		return b, err
+	}
+	if len(v.Meta) != 0 {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"meta\":"...)
+		if b, err = hrt.AppendJSONValue(b, v.Meta); err != nil {
+			return b, err
+		}
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"extra\":"...)
+	if b, err = hrt.AppendJSONValue(b, v.Extra); err != nil {
+		return b, err
+	}
+	if v.Note != nil {
+		if len(b) > n {
+			b = append(b, ',')
+		}
+		b = append(b, "\"note\":"...)
+		b = hrt.AppendJSONString(b, string(*v.Note))
+	}
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"\\u003chtml\\u003e\":"...)
+	b = hrt.AppendJSONString(b, string(v.HTML))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"Untagged\":"...)
+	b = strconv.AppendUint(b, uint64(v.Untagged), 10)
+	b = append(b, '}')
+	return b, nil
+}
+
+// CheckEnums implements hrt.EnumChecker.
+func (v Item) CheckEnums() error {
+	if err := hrt.CheckEnum("status", v.Status); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("owner", v.Owner); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("owners", v.Owners); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("meta", v.Meta); err != nil {
+		return err
+	}
+	if err := hrt.CheckEnumsOf("extra", v.Extra); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DecodeURL implements hrt.URLDecodable.
+func (v *Owner) DecodeURL(d *hrt.URLDecodeState) error {
+	p := d.Policy()
+	if err := d.JSON("name", &v.Name, p); err != nil {
+		return err
+	}
+	if err := d.JSON("status", &v.Status, p); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppendJSON implements hrt.JSONAppender.
+func (v Owner) AppendJSON(b []byte) ([]byte, error) {
+	b = append(b, '{')
+	n := len(b)
+	b = append(b, "\"name\":"...)
+	b = hrt.AppendJSONString(b, string(v.Name))
+	if len(b) > n {
+		b = append(b, ',')
+	}
+	b = append(b, "\"status\":"...)
+	if v.Status == nil {
+		b = append(b, "null"...)
+	} else {
+		b = hrt.AppendJSONString(b, string(*v.Status))
+	}
+	b = append(b, '}')
+	return b, nil
+}
+
+// CheckEnums implements hrt.EnumChecker.
+func (v Owner) CheckEnums() error {
+	if v.Status != nil {
+		if err := hrt.CheckEnum("status", *v.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
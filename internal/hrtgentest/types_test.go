@@ -0,0 +1,284 @@
+package hrtgentest
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"libdb.so/hrt/v2"
+)
+
+// Types without the generated methods, which hrt handles using reflection.
+type (
+	plainListRequest ListRequest
+	plainItem        Item
+)
+
+func TestDecodeURL(t *testing.T) {
+	parsers := hrt.Parsers{}
+	hrt.AddParser(parsers, func(s string) (Status, error) {
+		return Status(strings.ToLower(s)), nil
+	})
+
+	decoders := map[string]hrt.Decoder{
+		"default": hrt.URLDecoder,
+		"policy": hrt.NewURLDecoder(hrt.URLDecoderOpts{
+			Policy: hrt.ParsePolicy{Empty: hrt.EmptyZero, IntPrefixes: true},
+		}),
+		"parsers": hrt.NewURLDecoder(hrt.URLDecoderOpts{Parsers: parsers}),
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		query  url.Values
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "all",
+			params: map[string]string{"id": "42"},
+			query: url.Values{
+				"limit":    {"0x10"},
+				"offset":   {"0b11"},
+				"verbose":  {""},
+				"ratio":    {"0.25"},
+				"name":     {""},
+				"status":   {"ACTIVE"},
+				"statuses": {`["active","archived"]`},
+				"since":    {"2021-02-03"},
+				"timeout":  {"1m"},
+				"parent":   {"7"},
+				"code":     {"abc"},
+				"owner":    {"alice"},
+				"tags":     {`["a","b"]`},
+				"filter":   {"active", "ARCHIVED,active"},
+				"size":     {"1,2", "3"},
+				"SORT":     {"name"},
+				"Ignored":  {"x"},
+			},
+		},
+		{
+			name:  "empty values",
+			query: url.Values{"limit": {""}, "verbose": {"false"}, "name": {""}, "parent": {""}},
+		},
+		{
+			name:  "invalid int",
+			query: url.Values{"limit": {"many"}},
+		},
+		{
+			name:  "invalid uint",
+			query: url.Values{"offset": {"-1"}},
+		},
+		{
+			name:  "invalid bool",
+			query: url.Values{"verbose": {"maybe"}},
+		},
+		{
+			name:  "invalid float",
+			query: url.Values{"ratio": {"1,5"}},
+		},
+		{
+			name:  "invalid duration",
+			query: url.Values{"timeout": {"5"}},
+		},
+		{
+			name:  "invalid json",
+			query: url.Values{"tags": {"a,b"}},
+		},
+		{
+			name:  "invalid enum",
+			query: url.Values{"status": {"deleted"}},
+		},
+		{
+			name:  "empty slice",
+			query: url.Values{"size": {""}},
+		},
+		{
+			name:  "invalid slice element",
+			query: url.Values{"size": {"1,x"}},
+		},
+		{
+			name:  "invalid enum in query slice",
+			query: url.Values{"filter": {"active", "deleted"}},
+		},
+		{
+			name:  "invalid enum in slice",
+			query: url.Values{"statuses": {`["active","deleted"]`}},
+		},
+	}
+
+	for name, dec := range decoders {
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				r := newRequest(test.params, test.query)
+
+				var fast ListRequest
+				fastErr := dec.Decode(r, &fast)
+
+				r = newRequest(test.params, test.query)
+
+				var plain plainListRequest
+				plainErr := dec.Decode(r, &plain)
+
+				if errString(fastErr) != errString(plainErr) {
+					t.Errorf("unexpected error:\n expected: %v\n got: %v", plainErr, fastErr)
+				}
+				if !reflect.DeepEqual(fast, ListRequest(plain)) {
+					t.Errorf("unexpected request:\n expected: %+v\n got: %+v", plain, fast)
+				}
+			})
+		}
+	}
+}
+
+func newRequest(params map[string]string, query url.Values) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+
+	r := httptest.NewRequest("GET", "/?"+query.Encode(), nil)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAppendJSON(t *testing.T) {
+	status := StatusArchived
+	note := "note"
+
+	tests := []struct {
+		name  string
+		item  Item
+		error bool
+	}{
+		{
+			name: "zero",
+		},
+		{
+			name: "all",
+			item: Item{
+				ID:     -5,
+				Name:   "<a href=\"x\">&</a>\n\t\\ é \u2028 \x01 \xff",
+				Price:  0.1,
+				Score:  1e21,
+				Active: true,
+				Status: StatusActive,
+				Owner:  &Owner{Name: "alice", Status: &status},
+				Owners: []Owner{
+					{Name: "bob"},
+					{Name: "carol", Status: &status},
+				},
+				Tags:     []string{"a", "b"},
+				Matrix:   [][]int{{1, 2}, nil, {}},
+				Data:     []byte("data"),
+				Created:  time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC),
+				Meta:     map[string]any{"b": 1, "a": []int{2}},
+				Extra:    Owner{Name: "dave"},
+				Note:     &note,
+				Hidden:   "hidden",
+				HTML:     "<html>",
+				Untagged: 65535,
+				internal: "internal",
+			},
+		},
+		{
+			name: "small floats",
+			item: Item{Price: 1e-7, Score: -1.5e-10},
+		},
+		{
+			name: "empty collections",
+			item: Item{Owners: []Owner{}, Tags: []string{}, Matrix: [][]int{}, Meta: map[string]any{}},
+		},
+		{
+			name:  "NaN",
+			item:  Item{Score: math.NaN()},
+			error: true,
+		},
+		{
+			name:  "infinite float32",
+			item:  Item{Price: float32(math.Inf(-1))},
+			error: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fast, fastErr := encode(test.item)
+			plain, plainErr := encode(plainItem(test.item))
+
+			if test.error {
+				if fastErr == nil || plainErr == nil {
+					t.Fatalf("expected errors, got %v and %v", fastErr, plainErr)
+				}
+				if fastErr.Error() != plainErr.Error() {
+					t.Errorf("unexpected error:\n expected: %v\n got: %v", plainErr, fastErr)
+				}
+				return
+			}
+
+			if fastErr != nil || plainErr != nil {
+				t.Fatalf("unexpected errors: %v, %v", fastErr, plainErr)
+			}
+			if fast != plain {
+				t.Errorf("unexpected body:\n expected: %s\n got: %s", plain, fast)
+			}
+		})
+	}
+}
+
+func encode(v any) (string, error) {
+	w := httptest.NewRecorder()
+	err := hrt.JSONEncoder.Encode(w, v)
+	return w.Body.String(), err
+}
+
+func TestCheckEnums(t *testing.T) {
+	tests := []string{
+		`{}`,
+		`{"status": "active", "owner": {"status": "archived"}}`,
+		`{"status": "deleted"}`,
+		`{"owner": {"status": "deleted"}}`,
+		`{"owners": [{"status": "active"}, {"status": "deleted"}]}`,
+		`{"extra": {"status": "deleted"}}`,
+	}
+
+	for _, body := range tests {
+		t.Run(body, func(t *testing.T) {
+			var fast Item
+			fastErr := hrt.JSONEncoder.Decode(newBodyRequest(body), &fast)
+
+			var plain plainItem
+			plainErr := hrt.JSONEncoder.Decode(newBodyRequest(body), &plain)
+
+			if errString(fastErr) != errString(plainErr) {
+				t.Errorf("unexpected error:\n expected: %v\n got: %v", plainErr, fastErr)
+			}
+		})
+	}
+}
+
+func newBodyRequest(body string) *http.Request {
+	return httptest.NewRequest("POST", "/", bytes.NewBufferString(body))
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+var (
+	_ hrt.URLDecodable = (*ListRequest)(nil)
+	_ hrt.JSONAppender = Item{}
+	_ hrt.EnumChecker  = Owner{}
+)
@@ -5,20 +5,81 @@ import (
 	"encoding"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+// Special values of ParseOpts.TimeLayout.
+const (
+	// TimeLayoutUnix parses times as Unix timestamps in seconds.
+	TimeLayoutUnix = "unix"
+	// TimeLayoutUnixMilli parses times as Unix timestamps in milliseconds.
+	TimeLayoutUnixMilli = "unixmilli"
+)
 
-// SetPrimitiveFromString sets the value of a primitive type from a string. It
-// supports strings, ints, uints, floats and bools. If s is empty, the value is
-// left untouched.
+// ParseOpts controls how SetFromString parses strings.
+type ParseOpts struct {
+	// BoolPresence makes a present but empty value set bools to true, like
+	// ?verbose. Non-empty values are still parsed strictly.
+	BoolPresence bool
+	// EmptyZero makes a present but empty value set the field to its zero
+	// value instead of leaving it untouched.
+	EmptyZero bool
+	// IntPrefixes allows integers to have a base prefix (0x, 0o, 0b) and
+	// underscores, as in Go literals.
+	IntPrefixes bool
+	// TimeLayout is the layout of time.Time values, which is either a layout
+	// for time.Parse, TimeLayoutUnix or TimeLayoutUnixMilli. If empty, times
+	// are parsed using their UnmarshalText method, i.e. as RFC 3339.
+	TimeLayout string
+	// Parsers maps types to functions that parse them. A parser takes
+	// precedence over all other ways of parsing its type and must return a
+	// value of exactly that type.
+	Parsers map[reflect.Type]func(string) (any, error)
+}
+
+// SetPrimitiveFromString sets the value of a primitive type from a string
+// using the default ParseOpts. If s is empty, the value is left untouched.
 func SetPrimitiveFromString(rf reflect.Type, rv reflect.Value, s string) error {
-	if s == "" {
+	return SetFromString(rf, rv, s, s != "", ParseOpts{})
+}
+
+// SetFromString sets the value of a primitive type from a string. It supports
+// strings, ints, uints, floats, bools, time.Duration, time.Time,
+// encoding.TextUnmarshaler and any type in opts.Parsers. present
+// reports whether the value was given at all; absent values leave the field
+// untouched. Bools are parsed using strconv.ParseBool, and numbers are parsed
+// independently of the locale.
+func SetFromString(rf reflect.Type, rv reflect.Value, s string, present bool, opts ParseOpts) error {
+	if !present {
 		return nil
 	}
 
+	if s == "" {
+		elem := rf
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		switch {
+		case opts.BoolPresence && elem.Kind() == reflect.Bool:
+			s = "true"
+		case opts.EmptyZero:
+			rv.Set(reflect.Zero(rf))
+			return nil
+		default:
+			return nil
+		}
+	}
+
 	if rf.Kind() == reflect.Ptr {
 		rf = rf.Elem()
 
@@ -27,13 +88,53 @@ func SetPrimitiveFromString(rf reflect.Type, rv reflect.Value, s string) error {
 		rv = newValue.Elem()
 	}
 
+	if parse, ok := opts.Parsers[rf]; ok {
+		v, err := parse(s)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", rf)
+		}
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	switch {
+	case rf == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.Wrap(err, "invalid duration")
+		}
+		rv.SetInt(int64(d))
+		return nil
+
+	case rf == timeType && opts.TimeLayout != "":
+		t, err := parseTime(s, opts.TimeLayout)
+		if err != nil {
+			return errors.Wrap(err, "invalid time")
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if reflect.PointerTo(rf).Implements(textUnmarshalerType) {
+		unmarshaler := rv.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(s)); err != nil {
+			return errors.Wrap(err, "text unmarshaling")
+		}
+		return nil
+	}
+
+	base := 10
+	if opts.IntPrefixes {
+		base = 0
+	}
+
 	switch rf.Kind() {
 	case reflect.String:
 		rv.SetString(s)
 		return nil
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		i, err := strconv.ParseInt(s, 10, rf.Bits())
+		i, err := strconv.ParseInt(s, base, rf.Bits())
 		if err != nil {
 			return errors.Wrap(err, "invalid int")
 		}
@@ -41,7 +142,7 @@ func SetPrimitiveFromString(rf reflect.Type, rv reflect.Value, s string) error {
 		return nil
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		i, err := strconv.ParseUint(s, 10, rf.Bits())
+		i, err := strconv.ParseUint(s, base, rf.Bits())
 		if err != nil {
 			return errors.Wrap(err, "invalid uint")
 		}
@@ -57,22 +158,118 @@ func SetPrimitiveFromString(rf reflect.Type, rv reflect.Value, s string) error {
 		return nil
 
 	case reflect.Bool:
-		// False means omitted according to MDN.
-		rv.SetBool(s != "")
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Wrap(err, "invalid bool")
+		}
+		rv.SetBool(b)
 		return nil
 	}
 
-	if reflect.PointerTo(rf).Implements(textUnmarshalerType) {
-		unmarshaler := rv.Addr().Interface().(encoding.TextUnmarshaler)
-		if err := unmarshaler.UnmarshalText([]byte(s)); err != nil {
-			return errors.Wrap(err, "text unmarshaling")
+	return nil
+}
+
+// SetSliceFromStrings sets a slice of a type supported by SetFromString from
+// the given values. Each value may contain several comma-separated elements,
+// so ?sort=name&sort=id and ?sort=name,id are equivalent. If there are no
+// elements at all, the slice is handled like an empty value.
+func SetSliceFromStrings(rf reflect.Type, rv reflect.Value, vals []string, present bool, opts ParseOpts) error {
+	if !present {
+		return nil
+	}
+
+	var elems []string
+	for _, val := range vals {
+		if val != "" {
+			elems = append(elems, strings.Split(val, ",")...)
+		}
+	}
+
+	if len(elems) == 0 {
+		if opts.EmptyZero {
+			rv.Set(reflect.Zero(rf))
 		}
+		return nil
 	}
 
+	slice := reflect.MakeSlice(rf, len(elems), len(elems))
+	for i, elem := range elems {
+		if err := SetFromString(rf.Elem(), slice.Index(i), elem, true, opts); err != nil {
+			return errors.Wrapf(err, "element %d", i)
+		}
+	}
+	rv.Set(slice)
 	return nil
 }
 
+func parseTime(s, layout string) (time.Time, error) {
+	switch layout {
+	case TimeLayoutUnix, TimeLayoutUnixMilli:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if layout == TimeLayoutUnix {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		return time.UnixMilli(n).UTC(), nil
+	default:
+		return time.Parse(layout, s)
+	}
+}
+
+// FormatToString formats the value of a primitive type as a string such that
+// SetFromString parses it back into the same value. It returns false if the
+// value is a nil pointer or its type is not supported.
+func FormatToString(rv reflect.Value, opts ParseOpts) (string, bool) {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", false
+		}
+		rv = rv.Elem()
+	}
+
+	rf := rv.Type()
+	switch {
+	case rf == durationType:
+		return time.Duration(rv.Int()).String(), true
+
+	case rf == timeType && opts.TimeLayout != "":
+		t := rv.Interface().(time.Time)
+		switch opts.TimeLayout {
+		case TimeLayoutUnix:
+			return strconv.FormatInt(t.Unix(), 10), true
+		case TimeLayoutUnixMilli:
+			return strconv.FormatInt(t.UnixMilli(), 10), true
+		default:
+			return t.Format(opts.TimeLayout), true
+		}
+	}
+
+	if rf.Implements(textMarshalerType) {
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil
+	}
+
+	switch rf.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rf.Bits()), true
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true
+	default:
+		return "", false
+	}
+}
+
 // EachStructField calls the given function for each field of the given struct.
+// Fields of embedded structs without a struct tag are visited as if they were
+// fields of the outer struct.
 func EachStructField(v any, f func(reflect.StructField, reflect.Value) error) error {
 	rv := reflect.Indirect(reflect.ValueOf(v))
 	if !rv.IsValid() {
@@ -83,12 +280,24 @@ func EachStructField(v any, f func(reflect.StructField, reflect.Value) error) er
 		return errors.New("value is not a struct")
 	}
 
+	return eachStructField(rv, f)
+}
+
+func eachStructField(rv reflect.Value, f func(reflect.StructField, reflect.Value) error) error {
 	rt := rv.Type()
 	nfields := rv.NumField()
 
 	for i := 0; i < nfields; i++ {
 		rfv := rv.Field(i)
 		rft := rt.Field(i)
+
+		if isEmbeddedStruct(rft) {
+			if err := eachStructField(rfv, f); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if !rft.IsExported() {
 			continue
 		}
@@ -100,3 +309,46 @@ func EachStructField(v any, f func(reflect.StructField, reflect.Value) error) er
 
 	return nil
 }
+
+// EachStructFieldType is like EachStructField, but walks the fields of the
+// struct type rt instead of a value. The index of each field is relative to
+// rt, so it can be used with reflect.Value.FieldByIndex.
+func EachStructFieldType(rt reflect.Type, f func(reflect.StructField, []int) error) error {
+	if rt.Kind() != reflect.Struct {
+		return errors.New("type is not a struct")
+	}
+	return eachStructFieldType(rt, nil, f)
+}
+
+func eachStructFieldType(rt reflect.Type, index []int, f func(reflect.StructField, []int) error) error {
+	nfields := rt.NumField()
+
+	for i := 0; i < nfields; i++ {
+		rft := rt.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if isEmbeddedStruct(rft) {
+			if err := eachStructFieldType(rft.Type, fieldIndex, f); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !rft.IsExported() {
+			continue
+		}
+
+		if err := f(rft, fieldIndex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isEmbeddedStruct(rft reflect.StructField) bool {
+	return rft.Anonymous &&
+		rft.Tag == "" &&
+		rft.Type.Kind() == reflect.Struct &&
+		!reflect.PointerTo(rft.Type).Implements(textUnmarshalerType)
+}
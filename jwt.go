@@ -0,0 +1,515 @@
+package hrt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Supported JWT signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// JWTKey is a key used to verify the signature of a JWT.
+type JWTKey struct {
+	// ID is the key ID. If the token has a kid header, then only keys with a
+	// matching ID are used.
+	ID string
+	// Algorithm is the algorithm that the key verifies. Tokens signed with any
+	// other algorithm are never verified using this key.
+	Algorithm string
+	// Key is the verification key. It must be a []byte for HS256, an
+	// *rsa.PublicKey for RS256 and an *ecdsa.PublicKey for ES256.
+	Key any
+}
+
+// JWTKeySet is a set of keys used to verify JWTs.
+type JWTKeySet []JWTKey
+
+// LoadJWKSFile loads a JSON Web Key Set from the given file. See ParseJWKS.
+func LoadJWKSFile(path string) (JWTKeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read JWKS file")
+	}
+	return ParseJWKS(b)
+}
+
+// ParseJWKS parses a JSON Web Key Set. RSA, EC (P-256) and oct keys are
+// supported. Keys that are not meant for signing and keys of other types or
+// curves, such as OKP or P-384 keys, are skipped. An error is returned if a
+// supported key is malformed or if no usable key remains.
+func ParseJWKS(b []byte) (JWTKeySet, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal JWKS")
+	}
+
+	keys := make(JWTKeySet, 0, len(jwks.Keys))
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.toKey()
+		if err != nil {
+			if errors.As(err, new(unsupportedJWKError)) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "key %d", i)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no supported signing keys")
+	}
+
+	return keys, nil
+}
+
+// unsupportedJWKError is returned by jsonWebKey.toKey for keys of a type or
+// curve that is not supported, which ParseJWKS skips.
+type unsupportedJWKError struct {
+	msg string
+}
+
+func (e unsupportedJWKError) Error() string {
+	return e.msg
+}
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+func (jwk jsonWebKey) toKey() (JWTKey, error) {
+	key := JWTKey{
+		ID:        jwk.KeyID,
+		Algorithm: jwk.Algorithm,
+	}
+
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return key, errors.Wrap(err, "invalid RSA modulus")
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return key, errors.Wrap(err, "invalid RSA exponent")
+		}
+		if !e.IsInt64() || e.Int64() > math.MaxInt32 {
+			return key, errors.New("RSA exponent too large")
+		}
+		key.Key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if key.Algorithm == "" {
+			key.Algorithm = RS256
+		}
+
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return key, unsupportedJWKError{fmt.Sprintf("unsupported EC curve %q", jwk.Curve)}
+		}
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return key, errors.Wrap(err, "invalid EC x coordinate")
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return key, errors.Wrap(err, "invalid EC y coordinate")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return key, errors.New("EC point is not on curve")
+		}
+		key.Key = pub
+		if key.Algorithm == "" {
+			key.Algorithm = ES256
+		}
+
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return key, errors.Wrap(err, "invalid symmetric key")
+		}
+		key.Key = k
+		if key.Algorithm == "" {
+			key.Algorithm = HS256
+		}
+
+	default:
+		return key, unsupportedJWKError{fmt.Sprintf("unsupported key type %q", jwk.KeyType)}
+	}
+
+	return key, nil
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWTClaims contains the registered claims of a JWT, as well as the scope
+// (RFC 8693) and roles (RFC 9068) claims. It may be embedded into a
+// user-supplied claims struct.
+//
+// Use the Principal method to use the claims as a Principal.
+type JWTClaims struct {
+	Issuer    string          `json:"iss,omitempty"`
+	Subject   string          `json:"sub,omitempty"`
+	Audience  JWTAudience     `json:"aud,omitempty"`
+	ExpiresAt *JWTNumericDate `json:"exp,omitempty"`
+	NotBefore *JWTNumericDate `json:"nbf,omitempty"`
+	IssuedAt  *JWTNumericDate `json:"iat,omitempty"`
+	ID        string          `json:"jti,omitempty"`
+	// Scope is a space-delimited list of scopes.
+	Scope string   `json:"scope,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Principal returns the claims as a Principal whose subject is the sub claim.
+func (c JWTClaims) Principal() Principal {
+	return jwtPrincipal{c}
+}
+
+type jwtPrincipal struct{ JWTClaims }
+
+var _ Principal = jwtPrincipal{}
+
+func (p jwtPrincipal) Subject() string {
+	return p.JWTClaims.Subject
+}
+
+// HasScope returns true if the scope claim contains the given scope.
+func (c JWTClaims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole returns true if the roles claim contains the given role.
+func (c JWTClaims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTAudience is the aud claim of a JWT. It may be either a single string or
+// an array of strings.
+type JWTAudience []string
+
+// Contains returns true if the audience contains the given value.
+func (a JWTAudience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a JWTAudience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *JWTAudience) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*a = JWTAudience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// JWTNumericDate is a JWT NumericDate: the number of seconds since the Unix
+// epoch, possibly fractional.
+type JWTNumericDate struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d JWTNumericDate) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprint(d.Unix())), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *JWTNumericDate) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return errors.Wrap(err, "invalid NumericDate")
+	}
+	sec, frac := math.Modf(f)
+	d.Time = time.Unix(int64(sec), int64(frac*1e9))
+	return nil
+}
+
+// JWTOpts contains options for verifying JWTs.
+type JWTOpts struct {
+	// Keys is the set of keys used to verify tokens.
+	Keys JWTKeySet
+	// Issuer, if not empty, is the required value of the iss claim.
+	Issuer string
+	// Audience, if not empty, must be contained in the aud claim.
+	Audience string
+	// Leeway is the allowed clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// Realm is the realm reported in the WWW-Authenticate header.
+	Realm string
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// VerifyJWT creates a middleware that verifies the bearer token of each
+// request and decodes its claims into ClaimsT. Handlers can retrieve the claims
+// using JWTClaimsFromContext.
+//
+// The claims are also stored as the request's Principal. If ClaimsT does not
+// implement Principal, then the token's JWTClaims are used instead.
+//
+// Requests without a valid token are rejected with a 401 error that is written
+// using the ErrorWriter in the request's Opts. A WWW-Authenticate header is
+// set as described in RFC 6750.
+func VerifyJWT[ClaimsT any](opts JWTOpts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				writeAuthError(w, r, opts.challenge(""), errors.New("missing bearer token"))
+				return
+			}
+
+			var claims ClaimsT
+			registered, err := opts.verify(token, &claims)
+			if err != nil {
+				writeAuthError(w, r, opts.challenge(err.Error()), errors.Wrap(err, "invalid token"))
+				return
+			}
+
+			principal, ok := any(claims).(Principal)
+			if !ok {
+				principal = registered.Principal()
+			}
+
+			ctx := context.WithValue(r.Context(), jwtClaimsCtxKey, claims)
+			ctx = WithPrincipal(ctx, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// JWTClaimsFromContext returns the claims decoded by VerifyJWT. False is
+// returned if there are no claims of type ClaimsT in the context.
+func JWTClaimsFromContext[ClaimsT any](ctx context.Context) (ClaimsT, bool) {
+	claims, ok := ctx.Value(jwtClaimsCtxKey).(ClaimsT)
+	return claims, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+func writeAuthError(w http.ResponseWriter, r *http.Request, challenge string, err error) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	OptsFromContext(r.Context()).ErrorWriter.WriteError(w, WrapHTTPError(http.StatusUnauthorized, err))
+}
+
+func (o JWTOpts) challenge(errDesc string) string {
+	var b strings.Builder
+	b.WriteString("Bearer")
+	if o.Realm != "" {
+		fmt.Fprintf(&b, " realm=%q,", o.Realm)
+	}
+	if errDesc != "" {
+		fmt.Fprintf(&b, " error=\"invalid_token\", error_description=%q", errDesc)
+	}
+	return strings.TrimSuffix(b.String(), ",")
+}
+
+func (o JWTOpts) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// verify verifies the given token and unmarshals its claims into dst. The
+// registered claims are returned.
+func (o JWTOpts) verify(token string, dst any) (JWTClaims, error) {
+	var claims JWTClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return claims, errors.Wrap(err, "invalid header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errors.New("invalid signature encoding")
+	}
+
+	if err := o.verifySignature(header.Algorithm, header.KeyID, parts[0]+"."+parts[1], sig); err != nil {
+		return claims, err
+	}
+
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return claims, errors.Wrap(err, "invalid claims")
+	}
+	if err := o.checkClaims(claims); err != nil {
+		return claims, err
+	}
+
+	if err := decodeJWTSegment(parts[1], dst); err != nil {
+		return claims, errors.Wrap(err, "invalid claims")
+	}
+
+	return claims, nil
+}
+
+func (o JWTOpts) verifySignature(alg, kid, signed string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signed))
+
+	var matched bool
+	for _, key := range o.Keys {
+		if key.Algorithm != alg || (kid != "" && key.ID != kid) {
+			continue
+		}
+		matched = true
+
+		if verifyJWTSignature(key, signed, sum[:], sig) {
+			return nil
+		}
+	}
+
+	if !matched {
+		return fmt.Errorf("no key for algorithm %q", alg)
+	}
+	return errors.New("signature verification failed")
+}
+
+func verifyJWTSignature(key JWTKey, signed string, sum, sig []byte) bool {
+	switch key.Algorithm {
+	case HS256:
+		secret, ok := key.Key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return hmac.Equal(mac.Sum(nil), sig)
+
+	case RS256:
+		pub, ok := key.Key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum, sig) == nil
+
+	case ES256:
+		pub, ok := key.Key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, sum, r, s)
+
+	default:
+		return false
+	}
+}
+
+func (o JWTOpts) checkClaims(claims JWTClaims) error {
+	now := o.now()
+
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(o.Leeway)) {
+		return errors.New("token is expired")
+	}
+
+	if claims.NotBefore != nil && now.Add(o.Leeway).Before(claims.NotBefore.Time) {
+		return errors.New("token is not valid yet")
+	}
+
+	if o.Issuer != "" && claims.Issuer != o.Issuer {
+		return errors.New("invalid issuer")
+	}
+
+	if o.Audience != "" && !claims.Audience.Contains(o.Audience) {
+		return errors.New("invalid audience")
+	}
+
+	return nil
+}
+
+func decodeJWTSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,248 @@
+package hrt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	JWTClaims
+	Name string `json:"name"`
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	hsKey := []byte("secret")
+	rsKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := JWTOpts{
+		Keys: JWTKeySet{
+			{ID: "hs", Algorithm: HS256, Key: hsKey},
+			{ID: "rs", Algorithm: RS256, Key: &rsKey.PublicKey},
+			{ID: "es", Algorithm: ES256, Key: &esKey.PublicKey},
+		},
+		Issuer:   "hrt",
+		Audience: "api",
+		Realm:    "test",
+		Now:      func() time.Time { return now },
+	}
+
+	claims := map[string]any{
+		"iss":  "hrt",
+		"aud":  []string{"api", "other"},
+		"exp":  now.Add(time.Hour).Unix(),
+		"name": "diamondburned",
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+		body   string
+	}{
+		{
+			name:   "HS256",
+			token:  signTestJWT(t, HS256, "hs", hsKey, claims),
+			status: 200,
+			body:   "diamondburned",
+		},
+		{
+			name:   "RS256",
+			token:  signTestJWT(t, RS256, "rs", rsKey, claims),
+			status: 200,
+			body:   "diamondburned",
+		},
+		{
+			name:   "ES256",
+			token:  signTestJWT(t, ES256, "", esKey, claims),
+			status: 200,
+			body:   "diamondburned",
+		},
+		{
+			name:   "missing token",
+			status: 401,
+			body:   `{"error":"401: missing bearer token"}`,
+		},
+		{
+			name:   "wrong key",
+			token:  signTestJWT(t, HS256, "hs", []byte("wrong"), claims),
+			status: 401,
+			body:   `{"error":"401: invalid token: signature verification failed"}`,
+		},
+		{
+			name:   "algorithm mismatch",
+			token:  signTestJWT(t, HS256, "rs", hsKey, claims),
+			status: 401,
+			body:   `{"error":"401: invalid token: no key for algorithm \"HS256\""}`,
+		},
+		{
+			name:   "expired",
+			token:  signTestJWT(t, HS256, "hs", hsKey, withClaim(claims, "exp", now.Add(-time.Minute).Unix())),
+			status: 401,
+			body:   `{"error":"401: invalid token: token is expired"}`,
+		},
+		{
+			name:   "not yet valid",
+			token:  signTestJWT(t, HS256, "hs", hsKey, withClaim(claims, "nbf", now.Add(time.Minute).Unix())),
+			status: 401,
+			body:   `{"error":"401: invalid token: token is not valid yet"}`,
+		},
+		{
+			name:   "wrong audience",
+			token:  signTestJWT(t, HS256, "hs", hsKey, withClaim(claims, "aud", "other")),
+			status: 401,
+			body:   `{"error":"401: invalid token: invalid audience"}`,
+		},
+		{
+			name:   "wrong issuer",
+			token:  signTestJWT(t, HS256, "hs", hsKey, withClaim(claims, "iss", "evil")),
+			status: 401,
+			body:   `{"error":"401: invalid token: invalid issuer"}`,
+		},
+	}
+
+	handler := VerifyJWT[testClaims](opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := JWTClaimsFromContext[testClaims](r.Context())
+		if !ok {
+			t.Error("missing claims in context")
+		}
+		fmt.Fprint(w, claims.Name)
+	}))
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+			if test.status == 401 && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), `Bearer realm="test"`) {
+				t.Errorf("unexpected WWW-Authenticate header %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	esKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"EC","kid":"es","crv":"P-256","x":%q,"y":%q},
+		{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		{"kty":"EC","kid":"es384","crv":"P-384","x":"AQAB","y":"AQAB"},
+		{"kty":"oct","kid":"hs","k":%q},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}
+	]}`, b64(esKey.X.Bytes()), b64(esKey.Y.Bytes()), b64([]byte("secret")))
+
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].Algorithm != ES256 || !keys[0].Key.(*ecdsa.PublicKey).Equal(&esKey.PublicKey) {
+		t.Errorf("unexpected EC key %+v", keys[0])
+	}
+	if keys[1].Algorithm != HS256 || string(keys[1].Key.([]byte)) != "secret" {
+		t.Errorf("unexpected oct key %+v", keys[1])
+	}
+
+	invalid := map[string]string{
+		"no usable key":     `{"keys":[{"kty":"OKP","crv":"Ed25519","x":"AQAB"}]}`,
+		"malformed RSA key": `{"keys":[{"kty":"oct","k":"c2VjcmV0"},{"kty":"RSA","n":"!","e":"AQAB"}]}`,
+	}
+	for name, jwks := range invalid {
+		if _, err := ParseJWKS([]byte(jwks)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func withClaim(claims map[string]any, k string, v any) map[string]any {
+	c := make(map[string]any, len(claims)+1)
+	for k, v := range claims {
+		c[k] = v
+	}
+	c[k] = v
+	return c
+}
+
+func signTestJWT(t *testing.T, alg, kid string, key any, claims any) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+
+	segment := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := segment(header) + "." + segment(claims)
+	sum := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
@@ -0,0 +1,224 @@
+package hrt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+// LintCheck identifies the check that reported a LintIssue.
+type LintCheck uint8
+
+const (
+	// LintInvalidTags reports request types whose tags cannot be parsed.
+	LintInvalidTags LintCheck = iota
+	// LintUnknownURLParam reports `url` tags that do not match any {param}
+	// in the pattern of the route, which are therefore never populated.
+	LintUnknownURLParam
+	// LintUnusedPatternParam reports {params} in the pattern of a GET route
+	// that no field of the request type is decoded from.
+	LintUnusedPatternParam
+	// LintJSONOnlyGET reports GET routes whose request types only have
+	// fields that must be encoded as JSON. GET requests have no body, so
+	// such types are likely meant for another method.
+	LintJSONOnlyGET
+	// LintDuplicateKey reports fields that are decoded from the same key,
+	// including keys of different sources such as `url:"id"` and
+	// `query:"id"`.
+	LintDuplicateKey
+	// LintUnsupportedType reports fields of GET request types that URLDecoder
+	// silently ignores because their types cannot be parsed from a string.
+	// Types that are handled by a custom parser in Parsers are reported as
+	// well, since Lint does not know about them.
+	LintUnsupportedType
+)
+
+// LintIssue is a problem found by Lint.
+type LintIssue struct {
+	// Method is the HTTP method of the route.
+	Method string
+	// Pattern is the full chi pattern of the route.
+	Pattern string
+	// Field is the name of the offending field, if any.
+	Field string
+	// Check is the check that reported the issue.
+	Check LintCheck
+	// Message describes the issue.
+	Message string
+}
+
+// String formats the issue as "METHOD /pattern: field Name: message".
+func (i LintIssue) String() string {
+	s := i.Method + " " + i.Pattern + ": "
+	if i.Field != "" {
+		s += "field " + i.Field + ": "
+	}
+	return s + i.Message
+}
+
+// Lint walks the routes of r and checks the request type of each Handler
+// against the route's pattern. It is meant to be run in tests to catch
+// mistakes that would otherwise only show up as fields that are silently left
+// empty:
+//
+//	func TestRoutes(t *testing.T) {
+//		for _, issue := range hrt.Lint(newRouter()) {
+//			t.Error(issue)
+//		}
+//	}
+//
+// The checks that only apply to URLDecoder, such as LintUnusedPatternParam,
+// are limited to GET routes, which DefaultEncoder decodes using URLDecoder.
+// Routes whose handlers cannot be introspected or whose request types are not
+// structs are skipped. Issues are sorted like Routes.
+func Lint(r Router) []LintIssue {
+	routes, err := Routes(r)
+	if err != nil {
+		return []LintIssue{{Message: err.Error()}}
+	}
+
+	var issues []LintIssue
+	for _, ri := range routes {
+		issues = append(issues, lintRoute(ri)...)
+	}
+	return issues
+}
+
+// lintField is a field of a request type as seen by Lint.
+type lintField struct {
+	name   string
+	typ    reflect.Type
+	source fieldSource
+	key    string
+}
+
+func lintRoute(ri RouteInfo) []LintIssue {
+	hi, ok := ri.Introspect()
+	if !ok || hi.RequestType == nil {
+		return nil
+	}
+
+	rt := hi.RequestType
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct || rt == reflect.TypeOf(None{}) {
+		return nil
+	}
+
+	var issues []LintIssue
+	report := func(check LintCheck, field, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Method:  ri.Method,
+			Pattern: ri.Pattern,
+			Field:   field,
+			Check:   check,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if err := validateRequestType(rt); err != nil {
+		report(LintInvalidTags, "", "invalid request type %s: %v", rt, err)
+		return issues
+	}
+
+	var fields []lintField
+	rfutil.EachStructFieldType(rt, func(rft reflect.StructField, _ []int) error {
+		if rft.Type == ifMatchType {
+			return nil
+		}
+		source, key, _ := fieldTag(rft)
+		if source == sourceJSON && key == "-" {
+			return nil
+		}
+		fields = append(fields, lintField{rft.Name, rft.Type, source, key})
+		return nil
+	})
+
+	params := patternParams(ri.Pattern)
+	isGET := ri.Method == "GET"
+
+	for _, f := range fields {
+		if f.source == sourceURL && !containsString(params, f.key) {
+			report(LintUnknownURLParam, f.name, "url parameter %q is not in the pattern", f.key)
+		}
+	}
+
+	for i, f := range fields {
+		for _, prev := range fields[:i] {
+			if f.sharesKeyWith(prev) {
+				report(LintDuplicateKey, f.name, "key %q is also used by field %s", f.key, prev.name)
+				break
+			}
+		}
+	}
+
+	if !isGET {
+		return issues
+	}
+
+	for _, param := range params {
+		used := false
+		for _, f := range fields {
+			if f.readsParam(param) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			report(LintUnusedPatternParam, "", "pattern parameter %q is not decoded into any field", param)
+		}
+	}
+
+	jsonOnly := len(fields) > 0
+	for _, f := range fields {
+		if f.source != sourceJSON || isParsablePrimitive(f.typ) {
+			jsonOnly = false
+			break
+		}
+	}
+	if jsonOnly {
+		report(LintJSONOnlyGET, "", "request type %s only has JSON fields, but GET requests have no body", rt)
+	}
+
+	for _, f := range fields {
+		if f.source != sourceJSON && !isParsablePrimitive(f.typ) && !isParsableSlice(f.typ) {
+			report(LintUnsupportedType, f.name, "type %s cannot be decoded from a URL value and is ignored", f.typ)
+		}
+	}
+
+	return issues
+}
+
+// readsParam returns true if URLDecoder may decode the field from the chi URL
+// parameter with the given key.
+func (f lintField) readsParam(key string) bool {
+	switch f.source {
+	case sourceURL, sourceJSON:
+		return f.key == key
+	case sourceName:
+		return strings.EqualFold(f.key, key)
+	default:
+		return false
+	}
+}
+
+// sharesKeyWith returns true if both fields may be decoded from the same key.
+// Keys of untagged fields are matched case-insensitively.
+func (f lintField) sharesKeyWith(other lintField) bool {
+	if f.source == sourceName || other.source == sourceName {
+		return strings.EqualFold(f.key, other.key)
+	}
+	return f.key == other.key
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func lintHandler[T any]() Handler[T, None] {
+	return func(ctx context.Context, req T) (None, error) { return None{}, nil }
+}
+
+func TestLint(t *testing.T) {
+	type getUser struct {
+		ID      int    `url:"id"`
+		Verbose bool   `query:"verbose"`
+		Name    string `json:"name"`
+		IfMatch IfMatch
+	}
+
+	type typo struct {
+		ID int `url:"uid"`
+	}
+
+	type untagged struct {
+		ID int
+	}
+
+	type jsonParam struct {
+		ID int `json:"id"`
+	}
+
+	type filter struct {
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+	}
+
+	type duplicate struct {
+		ID    int    `url:"id"`
+		QID   int    `query:"id"`
+		Name  string `form:"name"`
+		NAME  string
+		Skip  string `json:"-"`
+		Other string `json:"-"`
+	}
+
+	type unsupported struct {
+		Tags  []string          `query:"tags"`
+		Attrs map[string]string `query:"attrs"`
+		Inner struct{ X int }   `form:"inner"`
+		Ptr   *int              `query:"ptr"`
+		JSON  []int             `json:"json"`
+		Page  PageRequest
+	}
+
+	type badTags struct {
+		N int `query:"n,int=hex"`
+	}
+
+	tests := []struct {
+		name    string
+		route   func(r Router)
+		expects []string
+	}{
+		{
+			name: "valid",
+			route: func(r Router) {
+				r.Get("/users/{id:[0-9]{1,8}}", lintHandler[getUser]())
+				r.Get("/untagged/{id}", lintHandler[untagged]())
+				r.Get("/json/{id}", lintHandler[jsonParam]())
+				r.Get("/none/{id}", lintHandler[None]())
+				r.Post("/filter", lintHandler[filter]())
+				r.Put("/users/{id}", lintHandler[filter]())
+				r.Get("/plain", http.NotFoundHandler())
+				Mount[int, widget](r, "/widgets", &widgetStore{})
+			},
+		},
+		{
+			name: "unknown url param",
+			route: func(r Router) {
+				r.Post("/users/{id}", lintHandler[typo]())
+			},
+			expects: []string{
+				`POST /users/{id}: field ID: url parameter "uid" is not in the pattern`,
+			},
+		},
+		{
+			name: "unused pattern param",
+			route: func(r Router) {
+				r.Get("/users/{id}", lintHandler[typo]())
+			},
+			expects: []string{
+				`GET /users/{id}: field ID: url parameter "uid" is not in the pattern`,
+				`GET /users/{id}: pattern parameter "id" is not decoded into any field`,
+			},
+		},
+		{
+			name: "mounted",
+			route: func(r Router) {
+				r.Route("/orgs/{org}", func(r Router) {
+					r.Get("/users/{id}", lintHandler[getUser]())
+				})
+			},
+			expects: []string{
+				`GET /orgs/{org}/users/{id}: pattern parameter "org" is not decoded into any field`,
+			},
+		},
+		{
+			name: "json only get",
+			route: func(r Router) {
+				r.Get("/filter", lintHandler[filter]())
+			},
+			expects: []string{
+				`GET /filter: request type hrt.filter only has JSON fields, but GET requests have no body`,
+			},
+		},
+		{
+			name: "duplicate keys",
+			route: func(r Router) {
+				r.Get("/users/{id}", lintHandler[duplicate]())
+			},
+			expects: []string{
+				`GET /users/{id}: field QID: key "id" is also used by field ID`,
+				`GET /users/{id}: field NAME: key "NAME" is also used by field Name`,
+			},
+		},
+		{
+			name: "unsupported types",
+			route: func(r Router) {
+				r.Get("/unsupported", lintHandler[unsupported]())
+				r.Post("/unsupported", lintHandler[unsupported]())
+			},
+			expects: []string{
+				`GET /unsupported: field Attrs: type map[string]string cannot be decoded from a URL value and is ignored`,
+				`GET /unsupported: field Inner: type struct { X int } cannot be decoded from a URL value and is ignored`,
+				`GET /unsupported: field Page: type hrt.PageRequest cannot be decoded from a URL value and is ignored`,
+			},
+		},
+		{
+			name: "invalid tags",
+			route: func(r Router) {
+				r.Get("/bad", lintHandler[badTags]())
+			},
+			expects: []string{
+				`GET /bad: invalid request type hrt.badTags: field N: unknown tag option "int=hex"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewRouter(DefaultOpts)
+			test.route(r)
+
+			var got []string
+			for _, issue := range Lint(r) {
+				got = append(got, issue.String())
+			}
+
+			if !reflect.DeepEqual(got, test.expects) {
+				t.Errorf("unexpected issues:\n expected: %q\n got: %q", test.expects, got)
+			}
+		})
+	}
+}
+
+func TestLint_check(t *testing.T) {
+	type request struct {
+		Attrs map[string]string `query:"attrs"`
+	}
+
+	r := NewRouter(DefaultOpts)
+	r.Get("/", lintHandler[request]())
+
+	issues := Lint(r)
+	if len(issues) != 1 || issues[0].Check != LintUnsupportedType || issues[0].Field != "Attrs" {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestPatternParams(t *testing.T) {
+	tests := map[string][]string{
+		"/":                          nil,
+		"/users/{id}":                {"id"},
+		"/users/{id}/posts/{post}":   {"id", "post"},
+		"/users/{id:[0-9]+}":         {"id"},
+		"/codes/{code:[a-z]{3}}/{n}": {"code", "n"},
+		"/files/*":                   nil,
+	}
+
+	for pattern, expect := range tests {
+		if got := patternParams(pattern); !reflect.DeepEqual(got, expect) {
+			t.Errorf("unexpected params of %q:\n expected: %q\n got: %q", pattern, expect, got)
+		}
+	}
+}
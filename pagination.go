@@ -0,0 +1,199 @@
+package hrt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// PageRequest contains the pagination parameters of a list request. It is
+// meant to be embedded into request types, in which case URLDecoder decodes
+// its fields from the query string.
+//
+// Both cursor-based and offset-based pagination are supported; handlers should
+// use whichever one they implement and ignore the other.
+//
+// # Example
+//
+//	type ListUsersRequest struct {
+//	    hrt.PageRequest
+//	    Name string `query:"name"`
+//	}
+//
+//	func handleListUsers(ctx context.Context, req ListUsersRequest) (hrt.Page[User], error) {
+//	    limit := req.PageLimit(20, 100)
+//	    ...
+//	}
+type PageRequest struct {
+	// Cursor is the opaque cursor of the page to fetch. It is empty for the
+	// first page.
+	Cursor string `query:"cursor"`
+	// Limit is the maximum number of items to return. Use PageLimit to get a
+	// value with defaults and caps applied.
+	Limit int `query:"limit"`
+	// Offset is the number of items to skip.
+	Offset int `query:"offset"`
+}
+
+// PageLimit returns the page limit, or def if no limit was given. The
+// returned limit is capped to max.
+func (p PageRequest) PageLimit(def, max int) int {
+	switch {
+	case p.Limit <= 0:
+		return def
+	case p.Limit > max:
+		return max
+	default:
+		return p.Limit
+	}
+}
+
+// Validate implements Validator.
+func (p PageRequest) Validate() error {
+	if p.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+	if p.Offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	return nil
+}
+
+// Page is a page of items returned by a list endpoint. When a handler returns
+// a Page, a Link header (RFC 8288) is automatically added to the response for
+// the next and previous pages.
+type Page[T any] struct {
+	// Items contains the items of this page.
+	Items []T `json:"items"`
+	// NextCursor is the cursor of the next page. It is empty if this is the
+	// last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// PrevCursor is the cursor of the previous page, if any.
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	// NextOffset is the offset of the next page for offset-based pagination.
+	// It is zero if this is the last page.
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+func (p Page[T]) pageLinks() pageLinks {
+	return pageLinks{
+		next: url.Values{
+			"cursor": optionalValue(p.NextCursor),
+			"offset": optionalValue(optionalInt(p.NextOffset)),
+		},
+		prev: url.Values{
+			"cursor": optionalValue(p.PrevCursor),
+		},
+	}
+}
+
+type pageLinks struct {
+	next url.Values
+	prev url.Values
+}
+
+type paginated interface {
+	pageLinks() pageLinks
+}
+
+func optionalValue(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return []string{v}
+}
+
+func optionalInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// setPageLinks sets the Link header of a paginated response.
+func setPageLinks(w http.ResponseWriter, r *http.Request, p paginated) {
+	links := p.pageLinks()
+	for _, rel := range []struct {
+		name   string
+		values url.Values
+	}{
+		{"next", links.next},
+		{"prev", links.prev},
+	} {
+		var set bool
+		query := r.URL.Query()
+		for k, v := range rel.values {
+			if v != nil {
+				query[k] = v
+				set = true
+			}
+		}
+		if !set {
+			continue
+		}
+
+		u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", u.String(), rel.name))
+	}
+}
+
+// CursorCodec encodes values into opaque pagination cursors that are signed
+// using HMAC-SHA256, so clients cannot forge or tamper with them.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a new CursorCodec with the given secret key.
+func NewCursorCodec(key []byte) CursorCodec {
+	return CursorCodec{key: key}
+}
+
+// Encode encodes v, which must be JSON-marshalable, into a cursor.
+func (c CursorCodec) Encode(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal cursor")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(b)), nil
+}
+
+// Decode decodes the given cursor into v. A 400 HTTPError is returned if the
+// cursor is malformed or its signature is invalid.
+func (c CursorCodec) Decode(cursor string, v any) error {
+	payload, sig, ok := bytes.Cut([]byte(cursor), []byte("."))
+	if !ok {
+		return NewHTTPError(http.StatusBadRequest, "malformed cursor")
+	}
+
+	b, err1 := base64.RawURLEncoding.DecodeString(string(payload))
+	s, err2 := base64.RawURLEncoding.DecodeString(string(sig))
+	if err1 != nil || err2 != nil {
+		return NewHTTPError(http.StatusBadRequest, "malformed cursor")
+	}
+
+	if !hmac.Equal(s, c.sign(b)) {
+		return NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return WrapHTTPError(http.StatusBadRequest, errors.Wrap(err, "invalid cursor"))
+	}
+
+	return nil
+}
+
+func (c CursorCodec) sign(b []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(b)
+	return mac.Sum(nil)[:16]
+}
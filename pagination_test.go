@@ -0,0 +1,120 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type listRequest struct {
+	PageRequest
+	Name string `query:"name"`
+}
+
+func TestPagination(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	items := []string{"a", "b", "c", "d", "e"}
+
+	r := NewRouter(DefaultOpts)
+	r.Get("/items", Wrap(func(ctx context.Context, req listRequest) (Page[string], error) {
+		var start int
+		if req.Cursor != "" {
+			if err := codec.Decode(req.Cursor, &start); err != nil {
+				return Page[string]{}, err
+			}
+		}
+
+		end := start + req.PageLimit(2, 3)
+		if end >= len(items) {
+			return Page[string]{Items: items[start:]}, nil
+		}
+
+		next, err := codec.Encode(end)
+		if err != nil {
+			return Page[string]{}, err
+		}
+
+		return Page[string]{Items: items[start:end], NextCursor: next}, nil
+	}))
+
+	cursor2, _ := codec.Encode(2)
+	cursor4, _ := codec.Encode(4)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		body   string
+		link   string
+	}{
+		{
+			name:   "first page",
+			query:  "name=x",
+			status: 200,
+			body:   `{"items":["a","b"],"next_cursor":"` + cursor2 + `"}`,
+			link:   `</items?cursor=` + cursor2 + `&name=x>; rel="next"`,
+		},
+		{
+			name:   "capped limit",
+			query:  "cursor=" + cursor2 + "&limit=10",
+			status: 200,
+			body:   `{"items":["c","d","e"]}`,
+		},
+		{
+			name:   "second page",
+			query:  "cursor=" + cursor2 + "&limit=2",
+			status: 200,
+			body:   `{"items":["c","d"],"next_cursor":"` + cursor4 + `"}`,
+			link:   `</items?cursor=` + cursor4 + `&limit=2>; rel="next"`,
+		},
+		{
+			name:   "tampered cursor",
+			query:  "cursor=" + "Mw" + cursor2[strings.Index(cursor2, "."):],
+			status: 400,
+			body:   `{"error":"400: invalid cursor"}`,
+		},
+		{
+			name:   "negative limit",
+			query:  "limit=-1",
+			status: 400,
+			body:   `{"error":"400: limit must not be negative"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/items?"+test.query, nil))
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+			if link := rec.Header().Get("Link"); link != test.link {
+				t.Errorf("unexpected Link header:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.link, link)
+			}
+		})
+	}
+}
+
+func TestURLDecoder_embedded(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?cursor=abc&limit=5&name=x", nil)
+
+	var got listRequest
+	if err := URLDecoder.Decode(req, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := listRequest{PageRequest: PageRequest{Cursor: "abc", Limit: 5}, Name: "x"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %+v, got %+v", expect, got)
+	}
+}
@@ -0,0 +1,647 @@
+package hrt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Optional is a value that distinguishes between being absent, being
+// explicitly null and having a value when decoded from JSON. It is meant for
+// the fields of PATCH requests, where a missing field must be left untouched
+// while a null field must be cleared:
+//
+//	type UpdateUserRequest struct {
+//	    Name  hrt.Optional[string] `json:"name"`
+//	    Email hrt.Optional[string] `json:"email"`
+//	}
+//
+// Absent fields are encoded as null, since omitempty has no effect on structs.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+type optionalState uint8
+
+const (
+	optionalStateAbsent optionalState = iota
+	optionalStateNull
+	optionalStateValue
+)
+
+// Some returns an Optional with the given value.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalStateValue}
+}
+
+// Null returns an Optional that is explicitly null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalStateNull}
+}
+
+// IsSet returns true if the value was present, even if it was null.
+func (o Optional[T]) IsSet() bool {
+	return o.state != optionalStateAbsent
+}
+
+// IsNull returns true if the value was explicitly null.
+func (o Optional[T]) IsNull() bool {
+	return o.state == optionalStateNull
+}
+
+// IsZero returns true if the value was absent.
+func (o Optional[T]) IsZero() bool {
+	return o.state == optionalStateAbsent
+}
+
+// Get returns the value and true if it was present and not null.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == optionalStateValue
+}
+
+// Or returns the value if it was present and not null, or def otherwise.
+func (o Optional[T]) Or(def T) T {
+	if o.state == optionalStateValue {
+		return o.value
+	}
+	return def
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.state != optionalStateValue {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if string(bytes.TrimSpace(b)) == "null" {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// Patch media types.
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Patch is a patch document decoded from a PATCH request by PatchDecoder. It is
+// either a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), depending on
+// the Content-Type of the request. Requests with a plain application/json body
+// are treated as merge patches.
+type Patch struct {
+	// ContentType is the media type of the patch, which is either
+	// MergePatchContentType or JSONPatchContentType.
+	ContentType string
+	// Body is the patch document.
+	Body json.RawMessage
+}
+
+// Apply applies the patch onto v, which must be a pointer to a value that
+// can be encoded as JSON. Fields are referred to by their JSON names. The
+// patched value's Enum fields are checked like a decoded request's would be.
+// v is only modified if the patch applies successfully.
+//
+// Fields that JSON does not represent, such as unexported fields and fields
+// tagged `json:"-"`, keep their values, including within nested structs that
+// the patch does not remove. Fields that the patch removes are reset to their
+// zero values.
+func (p Patch) Apply(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("patch target must be a non-nil pointer")
+	}
+
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode patch target")
+	}
+
+	switch p.ContentType {
+	case JSONPatchContentType:
+		doc, err = ApplyJSONPatch(doc, p.Body)
+	default:
+		doc, err = ApplyMergePatch(doc, p.Body)
+	}
+	if err != nil {
+		return err
+	}
+
+	var patched any
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return WrapHTTPError(http.StatusUnprocessableEntity, errors.Wrap(err, "patched value is invalid"))
+	}
+
+	nv := reflect.New(rv.Elem().Type())
+	nv.Elem().Set(patchTarget(rv.Elem(), patched))
+	if err := json.Unmarshal(doc, nv.Interface()); err != nil {
+		return WrapHTTPError(http.StatusUnprocessableEntity, errors.Wrap(err, "patched value is invalid"))
+	}
+	if err := checkEnums(nv.Interface()); err != nil {
+		return err
+	}
+
+	rv.Elem().Set(nv.Elem())
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// patchTarget returns the value that the patched document doc, as decoded into
+// an any, is unmarshaled into in place of orig. Structs are copied so that
+// they keep the fields that JSON does not represent, and their JSON fields are
+// prepared recursively. Everything else is reset, since json.Unmarshal would
+// otherwise merge into it, such as keeping map entries that the patch removed
+// or writing through pointers that are shared with orig.
+func patchTarget(orig reflect.Value, doc any) reflect.Value {
+	t := orig.Type()
+
+	obj, ok := doc.(map[string]any)
+	if !ok || t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return reflect.Zero(t)
+	}
+
+	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !orig.IsNil():
+		p := reflect.New(t.Elem())
+		p.Elem().Set(patchTarget(orig.Elem(), doc))
+		return p
+
+	case t.Kind() == reflect.Struct:
+		v := reflect.New(t).Elem()
+		v.Set(orig)
+		preparePatchFields(v, obj)
+		return v
+
+	default:
+		return reflect.Zero(t)
+	}
+}
+
+// preparePatchFields prepares the JSON fields of the struct v for
+// patchTarget. obj is the JSON object that v is unmarshaled from.
+func preparePatchFields(v reflect.Value, obj map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			switch {
+			case f.Type.Kind() == reflect.Struct:
+				preparePatchFields(fv, obj)
+				continue
+			case f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct:
+				if !fv.IsNil() && fv.CanSet() {
+					fv.Set(patchTarget(fv, obj))
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() || !fv.CanSet() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fv.Set(patchTarget(fv, obj[name]))
+	}
+}
+
+// PatchDecoder decodes PATCH requests. If the value is a *Patch, the request
+// body is read as a patch document according to its Content-Type, which must
+// be MergePatchContentType, JSONPatchContentType or application/json.
+// Otherwise, the body is decoded as JSON. DefaultEncoder uses PatchDecoder for
+// PATCH requests.
+var PatchDecoder Decoder = patchDecoder{}
+
+type patchDecoder struct{}
+
+func (d patchDecoder) Decode(r *http.Request, v any) error {
+	p, ok := v.(*Patch)
+	if !ok {
+		return JSONEncoder.Decode(r, v)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case MergePatchContentType, JSONPatchContentType:
+		p.ContentType = mediaType
+	case "", "application/json":
+		p.ContentType = MergePatchContentType
+	default:
+		return WrapHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Errorf("unsupported patch type %q", mediaType))
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read patch")
+	}
+	if !json.Valid(body) {
+		return errors.New("patch is not valid JSON")
+	}
+
+	p.Body = body
+	return nil
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) onto the JSON document
+// doc and returns the result.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	var d, p any
+	if err := unmarshalJSONValue(doc, &d); err != nil {
+		return nil, errors.Wrap(err, "invalid document")
+	}
+	if err := unmarshalJSONValue(patch, &p); err != nil {
+		return nil, WrapHTTPError(http.StatusBadRequest, errors.Wrap(err, "invalid merge patch"))
+	}
+	return json.Marshal(mergePatch(d, p))
+}
+
+func mergePatch(target, patch any) any {
+	po, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	to, ok := target.(map[string]any)
+	if !ok {
+		to = map[string]any{}
+	}
+
+	for k, v := range po {
+		if v == nil {
+			delete(to, k)
+		} else {
+			to[k] = mergePatch(to[k], v)
+		}
+	}
+
+	return to
+}
+
+// JSONPatchOp is a single operation of a JSON Patch (RFC 6902).
+type JSONPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies a JSON Patch (RFC 6902) onto the JSON document doc
+// and returns the result. A failed test operation results in a 409 Conflict
+// error; any other invalid operation results in a 422 Unprocessable Entity
+// error.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []JSONPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, WrapHTTPError(http.StatusBadRequest, errors.Wrap(err, "invalid JSON patch"))
+	}
+
+	var d any
+	if err := unmarshalJSONValue(doc, &d); err != nil {
+		return nil, errors.Wrap(err, "invalid document")
+	}
+
+	for i, op := range ops {
+		var err error
+		d, err = applyJSONPatchOp(d, op)
+		if err != nil {
+			return nil, WrapHTTPError(http.StatusUnprocessableEntity,
+				errors.Wrapf(err, "operation %d (%s %s)", i, op.Op, op.Path))
+		}
+	}
+
+	return json.Marshal(d)
+}
+
+func applyJSONPatchOp(doc any, op JSONPatchOp) (any, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		var v any
+		if err := unmarshalJSONValue(op.Value, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, path)
+		return doc, err
+
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = jsonPointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var v any
+		if op.Op == "move" {
+			if isJSONPointerPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into itself")
+			}
+			doc, v, err = jsonPointerRemove(doc, from)
+		} else {
+			v, err = jsonPointerGet(doc, from)
+			v = deepCopyJSON(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, v) {
+			return nil, NewHTTPError(http.StatusConflict, "test failed")
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// parseJSONPointer parses a JSON Pointer (RFC 6901) into its reference tokens.
+func parseJSONPointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", s)
+	}
+
+	parts := strings.Split(s[1:], "/")
+	for i, part := range parts {
+		part = strings.ReplaceAll(part, "~1", "/")
+		part = strings.ReplaceAll(part, "~0", "~")
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+func isJSONPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonPointerGet(doc any, path []string) (any, error) {
+	for _, key := range path {
+		switch v := doc.(type) {
+		case map[string]any:
+			child, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", key)
+			}
+			doc = child
+		case []any:
+			i, err := jsonArrayIndex(key, len(v)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("path member %q not found", key)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerAdd adds v at path and returns the new document.
+func jsonPointerAdd(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = v
+		return doc, nil
+	case []any:
+		i := len(p)
+		if key != "-" {
+			if i, err = jsonArrayIndex(key, len(p)); err != nil {
+				return nil, err
+			}
+		}
+		p = append(p, nil)
+		copy(p[i+1:], p[i:])
+		p[i] = v
+		return jsonPointerReplaceParent(doc, path[:len(path)-1], p)
+	default:
+		return nil, fmt.Errorf("cannot add member %q to a scalar", key)
+	}
+}
+
+// jsonPointerRemove removes the value at path and returns the new document
+// along with the removed value.
+func jsonPointerRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		v, ok := p[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q not found", key)
+		}
+		delete(p, key)
+		return doc, v, nil
+	case []any:
+		i, err := jsonArrayIndex(key, len(p)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := p[i]
+		p = append(p[:i:i], p[i+1:]...)
+		doc, err = jsonPointerReplaceParent(doc, path[:len(path)-1], p)
+		return doc, v, err
+	default:
+		return nil, nil, fmt.Errorf("path member %q not found", key)
+	}
+}
+
+// jsonPointerReplaceParent replaces the array at path with arr. It is needed
+// because slices may be reallocated when elements are added or removed.
+func jsonPointerReplaceParent(doc any, path []string, arr []any) (any, error) {
+	if len(path) == 0 {
+		return arr, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = arr
+	case []any:
+		i, _ := jsonArrayIndex(key, len(p)-1)
+		p[i] = arr
+	}
+	return doc, nil
+}
+
+func jsonArrayIndex(key string, max int) (int, error) {
+	if key != "0" && strings.HasPrefix(key, "0") {
+		return 0, fmt.Errorf("invalid array index %q", key)
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i > max {
+		return 0, fmt.Errorf("invalid array index %q", key)
+	}
+	return i, nil
+}
+
+// jsonEqual compares two decoded JSON values. Numbers are compared by value.
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, err1 := a.Float64()
+		bf, err2 := b.Float64()
+		return err1 == nil && err2 == nil && af == bf
+	default:
+		return a == b
+	}
+}
+
+func deepCopyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = deepCopyJSON(e)
+		}
+		return m
+	case []any:
+		a := make([]any, len(v))
+		for i, e := range v {
+			a[i] = deepCopyJSON(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// unmarshalJSONValue unmarshals b into v while preserving numbers as-is.
+func unmarshalJSONValue(b []byte, v *any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package hrt
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptional(t *testing.T) {
+	type request struct {
+		Name Optional[string] `json:"name"`
+		Age  Optional[int]    `json:"age"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		set   bool
+		null  bool
+		value string
+	}{
+		{"absent", `{}`, false, false, ""},
+		{"null", `{"name": null}`, true, true, ""},
+		{"zero", `{"name": ""}`, true, false, ""},
+		{"value", `{"name": "a"}`, true, false, "a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var req request
+			if err := json.Unmarshal([]byte(test.input), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Name.IsSet() != test.set || req.Name.IsNull() != test.null {
+				t.Errorf("expected set=%v null=%v, got set=%v null=%v",
+					test.set, test.null, req.Name.IsSet(), req.Name.IsNull())
+			}
+
+			if v := req.Name.Or(""); v != test.value {
+				t.Errorf("expected value %q, got %q", test.value, v)
+			}
+
+			if req.Age.IsSet() {
+				t.Error("expected age to be absent")
+			}
+		})
+	}
+
+	b, err := json.Marshal(request{Name: Some("a"), Age: Null[int]()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{"name":"a","age":null}`; string(b) != expect {
+		t.Errorf("unexpected body:\n expected: %s\n got: %s", expect, b)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		doc    string
+		patch  string
+		expect string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c","d":1}}`, `{"a":{"b":null,"e":2}}`, `{"a":{"d":1,"e":2}}`},
+		{`{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"n":12345678901234567890}`, `{}`, `{"n":12345678901234567890}`},
+	}
+
+	for _, test := range tests {
+		got, err := ApplyMergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error: %v", test.doc, test.patch, err)
+			continue
+		}
+		if string(got) != test.expect {
+			t.Errorf("%s + %s: unexpected body:\n expected: %s\n got: %s", test.doc, test.patch, test.expect, got)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		doc    string
+		patch  string
+		expect result[string]
+	}{
+		{
+			`{"a":1}`,
+			`[{"op":"add","path":"/b","value":2}]`,
+			okResult(`{"a":1,"b":2}`),
+		},
+		{
+			`{"a":[1,3]}`,
+			`[{"op":"add","path":"/a/1","value":2},{"op":"add","path":"/a/-","value":4}]`,
+			okResult(`{"a":[1,2,3,4]}`),
+		},
+		{
+			`{"a":[1,2,3],"b":"x"}`,
+			`[{"op":"remove","path":"/a/0"},{"op":"remove","path":"/b"}]`,
+			okResult(`{"a":[2,3]}`),
+		},
+		{
+			`{"a":{"b":1}}`,
+			`[{"op":"replace","path":"/a/b","value":"c"}]`,
+			okResult(`{"a":{"b":"c"}}`),
+		},
+		{
+			`{"a":{"b":1},"c":{}}`,
+			`[{"op":"move","from":"/a/b","path":"/c/d"}]`,
+			okResult(`{"a":{},"c":{"d":1}}`),
+		},
+		{
+			`{"a":{"b":[1]}}`,
+			`[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/-","value":2}]`,
+			okResult(`{"a":{"b":[1]},"c":[1,2]}`),
+		},
+		{
+			`{"a~b":1,"c/d":2}`,
+			`[{"op":"test","path":"/a~0b","value":1.0},{"op":"remove","path":"/c~1d"}]`,
+			okResult(`{"a~b":1}`),
+		},
+		{
+			`{"a":1}`,
+			`[{"op":"test","path":"/a","value":2}]`,
+			result[string]{error: "409: test failed"},
+		},
+		{
+			`{"a":1}`,
+			`[{"op":"remove","path":"/b"}]`,
+			result[string]{error: `422: operation 0 (remove /b): path member "b" not found`},
+		},
+		{
+			`{"a":[1]}`,
+			`[{"op":"add","path":"/a/2","value":1}]`,
+			result[string]{error: `422: operation 0 (add /a/2): invalid array index "2"`},
+		},
+		{
+			`{"a":{}}`,
+			`[{"op":"move","from":"/a","path":"/a/b"}]`,
+			result[string]{error: `422: operation 0 (move /a/b): cannot move a value into itself`},
+		},
+		{
+			`{}`,
+			`[{"op":"frobnicate","path":"/a"}]`,
+			result[string]{error: `422: operation 0 (frobnicate /a): unknown op "frobnicate"`},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ApplyJSONPatch([]byte(test.doc), []byte(test.patch))
+		if res := combineResult(string(got), err); res != test.expect {
+			t.Errorf("%s + %s: unexpected result:\n expected: %v\n got: %v", test.doc, test.patch, test.expect, res)
+		}
+	}
+}
+
+func TestPatchDecoder(t *testing.T) {
+	type item struct {
+		Name   string     `json:"name"`
+		Tags   []string   `json:"tags,omitempty"`
+		Status itemStatus `json:"status,omitempty"`
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		expect      result[item]
+	}{
+		{"", `{"name":"b"}`, okResult(item{Name: "b", Tags: []string{"x"}})},
+		{MergePatchContentType, `{"tags":null}`, okResult(item{Name: "a"})},
+		{JSONPatchContentType, `[{"op":"add","path":"/tags/-","value":"y"}]`, okResult(item{Name: "a", Tags: []string{"x", "y"}})},
+		{"text/plain", `{}`, result[item]{error: `415: unsupported patch type "text/plain"`}},
+		{MergePatchContentType, `{"status":"archived"}`, okResult(item{Name: "a", Tags: []string{"x"}, Status: "archived"})},
+		{MergePatchContentType, `{"status":"deleted"}`, result[item]{value: item{Name: "a", Tags: []string{"x"}}, error: `400: invalid status "deleted" (expected one of active, archived)`}},
+		{JSONPatchContentType, `[{"op":"add","path":"/status","value":"deleted"}]`, result[item]{value: item{Name: "a", Tags: []string{"x"}}, error: `400: invalid status "deleted" (expected one of active, archived)`}},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("PATCH", "/", strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+
+		var patch Patch
+		if err := PatchDecoder.Decode(req, &patch); err != nil {
+			if res := combineResult(item{}, err); res.error != test.expect.error {
+				t.Errorf("%s: unexpected error:\n expected: %v\n got: %v", test.body, test.expect.error, res.error)
+			}
+			continue
+		}
+
+		v := item{Name: "a", Tags: []string{"x"}}
+		err := patch.Apply(&v)
+
+		res := combineResult(v, err)
+		if res.error != test.expect.error || !equalItems(res.value, test.expect.value) {
+			t.Errorf("%s: unexpected result:\n expected: %+v\n got: %+v", test.body, test.expect, res)
+		}
+	}
+}
+
+func equalItems[T any](a, b T) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	return string(ab) == string(bb)
+}
+
+func TestPatch_hiddenFields(t *testing.T) {
+	type owner struct {
+		Name  string `json:"name"`
+		Token string `json:"-"`
+	}
+
+	type item struct {
+		Name    string            `json:"name"`
+		Secret  string            `json:"-"`
+		Meta    map[string]string `json:"meta,omitempty"`
+		Owner   *owner            `json:"owner,omitempty"`
+		version int
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		expect      item
+	}{
+		{
+			MergePatchContentType, `{"name":"b"}`,
+			item{Name: "b", Secret: "hash", Meta: map[string]string{"a": "1", "b": "2"}, Owner: &owner{"o", "token"}, version: 3},
+		},
+		{
+			MergePatchContentType, `{"meta":{"a":null},"owner":{"name":"p"}}`,
+			item{Name: "a", Secret: "hash", Meta: map[string]string{"b": "2"}, Owner: &owner{"p", "token"}, version: 3},
+		},
+		{
+			MergePatchContentType, `{"owner":null}`,
+			item{Name: "a", Secret: "hash", Meta: map[string]string{"a": "1", "b": "2"}, version: 3},
+		},
+		{
+			JSONPatchContentType, `[{"op":"remove","path":"/owner/name"},{"op":"remove","path":"/meta"}]`,
+			item{Name: "a", Secret: "hash", Owner: &owner{"", "token"}, version: 3},
+		},
+	}
+
+	for _, test := range tests {
+		orig := &owner{"o", "token"}
+		v := item{Name: "a", Secret: "hash", Meta: map[string]string{"a": "1", "b": "2"}, Owner: orig, version: 3}
+
+		if err := (Patch{test.contentType, json.RawMessage(test.body)}).Apply(&v); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.body, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.expect) {
+			t.Errorf("%s: unexpected result:\n expected: %+v\n got: %+v", test.body, test.expect, v)
+		}
+		if *orig != (owner{"o", "token"}) {
+			t.Errorf("%s: patch modified the original owner: %+v", test.body, *orig)
+		}
+	}
+}
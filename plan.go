@@ -0,0 +1,293 @@
+package hrt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+// fieldSource is where URLDecoder looks for the value of a field.
+type fieldSource uint8
+
+const (
+	// sourceForm is a form value, used by the form, query and schema tags.
+	sourceForm fieldSource = iota
+	// sourceURL is a chi URL parameter, used by the url tag.
+	sourceURL
+	// sourceJSON is a URL parameter or a form value containing JSON, used by
+	// the json tag.
+	sourceJSON
+	// sourceName is a URL parameter or form value whose key matches the
+	// field name case-insensitively, used by untagged fields.
+	sourceName
+)
+
+// fieldPlan describes how URLDecoder decodes a single field.
+type fieldPlan struct {
+	index  []int
+	typ    reflect.Type
+	source fieldSource
+	key    string
+	opts   rfutil.ParseOpts
+	// slice is true if the field takes all values of its key.
+	slice bool
+}
+
+// decodePlan describes how URLDecoder decodes a struct type. It is built once
+// per type and policy, so that tags are not parsed on every request.
+type decodePlan struct {
+	fields []fieldPlan
+	// byName is true if any field is looked up by its name.
+	byName bool
+}
+
+type decodePlanKey struct {
+	rt     reflect.Type
+	policy ParsePolicy
+}
+
+var decodePlans sync.Map // decodePlanKey -> *decodePlan or error
+
+// decodePlanOf returns the cached decoding plan of the struct type rt.
+func decodePlanOf(rt reflect.Type, policy ParsePolicy) (*decodePlan, error) {
+	key := decodePlanKey{rt, policy}
+	if v, ok := decodePlans.Load(key); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.(*decodePlan), nil
+	}
+
+	plan, err := compileDecodePlan(rt, policy)
+	if err != nil {
+		decodePlans.Store(key, err)
+		return nil, err
+	}
+
+	decodePlans.Store(key, plan)
+	return plan, nil
+}
+
+func compileDecodePlan(rt reflect.Type, policy ParsePolicy) (*decodePlan, error) {
+	plan := &decodePlan{}
+
+	err := rfutil.EachStructFieldType(rt, func(rft reflect.StructField, index []int) error {
+		if rft.Type == ifMatchType {
+			return nil // populated from headers
+		}
+
+		source, key, tagOpts := fieldTag(rft)
+		if source == sourceJSON && key == "-" {
+			return nil // explicitly ignored
+		}
+
+		p, err := policy.withTagOptions(tagOpts)
+		if err != nil {
+			return errors.Wrapf(err, "field %s", rft.Name)
+		}
+
+		plan.fields = append(plan.fields, fieldPlan{
+			index:  index,
+			typ:    rft.Type,
+			source: source,
+			key:    key,
+			opts:   p.parseOpts(),
+			slice:  isParsableSlice(rft.Type),
+		})
+		plan.byName = plan.byName || source == sourceName
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// fieldTag returns the source, key and tag options of a field. If a field has
+// several tags, the first one in the order form, query, schema, url and json
+// is used.
+func fieldTag(rft reflect.StructField) (source fieldSource, key, opts string) {
+	for _, tag := range []string{"form", "query", "schema"} {
+		if name, opts := parseTag(rft.Tag.Get(tag)); name != "" {
+			return sourceForm, name, opts
+		}
+	}
+
+	if name, opts := parseTag(rft.Tag.Get("url")); name != "" {
+		return sourceURL, name, opts
+	}
+
+	if name, _ := parseTag(rft.Tag.Get("json")); name != "" {
+		return sourceJSON, name, ""
+	}
+
+	return sourceName, rft.Name, ""
+}
+
+// decode decodes the request into the struct rv according to the plan.
+func (p *decodePlan) decode(r *http.Request, rv reflect.Value, parsers Parsers) error {
+	rctx := chi.RouteContext(r.Context())
+
+	var foldedForm map[string][]string
+	if p.byName {
+		foldedForm = foldForm(r)
+	}
+
+	for i := range p.fields {
+		f := &p.fields[i]
+		rfv := rv.FieldByIndex(f.index)
+
+		opts := f.opts
+		opts.Parsers = parsers
+
+		var vals []string
+		present := false
+		switch f.source {
+		case sourceForm:
+			vals, present = formValues(r, f.key)
+
+		case sourceURL:
+			var val string
+			val, present = urlParamFrom(rctx, f.key)
+			vals = []string{val}
+
+		case sourceJSON:
+			if err := decodeJSONField(r, rctx, f, rfv, opts); err != nil {
+				return err
+			}
+			continue
+
+		case sourceName:
+			vals, present = namedValues(r, rctx, f.key, foldedForm)
+		}
+
+		if err := setFromValues(f.typ, rfv, f.slice, vals, present, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setFromValues sets the field rv of type rt from the values of its key. If
+// slice is true and there is no parser for rt, the field takes all values.
+// Otherwise, it only takes the first one.
+func setFromValues(rt reflect.Type, rv reflect.Value, slice bool, vals []string, present bool, opts rfutil.ParseOpts) error {
+	if slice && !Parsers(opts.Parsers).has(rt) {
+		return rfutil.SetSliceFromStrings(rt, rv, vals, present, opts)
+	}
+
+	var val string
+	if len(vals) > 0 {
+		val = vals[0]
+	}
+	return rfutil.SetFromString(rt, rv, val, present, opts)
+}
+
+// decodeJSONField decodes a field with a json tag. A URL parameter is parsed
+// like any other value, while a form value is unmarshaled as JSON unless the
+// field is a string or has a parser.
+func decodeJSONField(r *http.Request, rctx *chi.Context, f *fieldPlan, rfv reflect.Value, opts rfutil.ParseOpts) error {
+	if val, ok := urlParamFrom(rctx, f.key); ok && val != "" {
+		return setFromValues(f.typ, rfv, f.slice, []string{val}, true, opts)
+	}
+
+	val, ok := formValue(r, f.key)
+	if !ok {
+		return nil
+	}
+
+	if Parsers(opts.Parsers).has(f.typ) {
+		return rfutil.SetFromString(f.typ, rfv, val, true, opts)
+	}
+
+	if f.typ.Kind() == reflect.String {
+		rfv.SetString(val)
+		return nil
+	}
+
+	jsonValue := reflect.New(f.typ)
+	if err := json.Unmarshal([]byte(val), jsonValue.Interface()); err != nil {
+		return errors.Wrap(err, "failed to unmarshal JSON")
+	}
+	rfv.Set(jsonValue.Elem())
+	return nil
+}
+
+// foldedURLParam returns the first chi URL parameter whose key matches the
+// given key case-insensitively.
+func foldedURLParam(rctx *chi.Context, key string) (string, bool) {
+	if rctx == nil {
+		return "", false
+	}
+	for i, k := range rctx.URLParams.Keys {
+		if strings.EqualFold(k, key) {
+			return rctx.URLParams.Values[i], true
+		}
+	}
+	return "", false
+}
+
+// foldForm parses the form of r and returns it with lowercased keys, which is
+// used to look up untagged fields case-insensitively.
+func foldForm(r *http.Request) map[string][]string {
+	// Trigger form parsing.
+	r.FormValue("")
+
+	folded := make(map[string][]string, len(r.Form))
+	for k, v := range r.Form {
+		folded[strings.ToLower(k)] = v
+	}
+	return folded
+}
+
+// namedValues returns the URL parameter or otherwise the form values whose key
+// matches the given field name case-insensitively. folded is the form as
+// returned by foldForm.
+func namedValues(r *http.Request, rctx *chi.Context, name string, folded map[string][]string) ([]string, bool) {
+	if val, ok := foldedURLParam(rctx, name); ok {
+		return []string{val}, true
+	}
+
+	vals, ok := r.Form[name]
+	if !ok {
+		vals, ok = folded[strings.ToLower(name)]
+	}
+	return vals, ok && len(vals) > 0
+}
+
+// validateRequestType checks the tags of the request type rt, which are
+// otherwise only checked when a request is decoded.
+func validateRequestType(rt reflect.Type) error {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if _, err := decodePlanOf(rt, ParsePolicy{}); err != nil {
+		return err
+	}
+	if _, err := defaultsOf(rt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// mustValidateRequestType panics if validateRequestType fails, so that
+// mistakes in tags surface when handlers are created rather than at request
+// time.
+func mustValidateRequestType(rt reflect.Type) {
+	if err := validateRequestType(rt); err != nil {
+		panic(fmt.Sprintf("hrt: invalid request type %s: %v", rt, err))
+	}
+}
@@ -0,0 +1,167 @@
+package hrt
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IfMatch holds the entity tags of a request's If-Match header. Request types
+// may declare a top-level field of type IfMatch, which is populated from the
+// header before the request is decoded. The field should be tagged with
+// `json:"-"` so that it cannot be set from the body.
+//
+// # Example
+//
+//	type UpdateUserRequest struct {
+//	    ID      int         `json:"id"`
+//	    Name    string      `json:"name"`
+//	    IfMatch hrt.IfMatch `json:"-"`
+//	}
+//
+//	func handleUpdateUser(ctx context.Context, req UpdateUserRequest) (User, error) {
+//	    user := getUser(req.ID)
+//	    if err := req.IfMatch.Check(user.ETag()); err != nil {
+//	        return User{}, err
+//	    }
+//	    ...
+//	}
+type IfMatch []string
+
+var ifMatchType = reflect.TypeFor[IfMatch]()
+
+// ParseIfMatch parses the If-Match header of the given request. Nil is
+// returned if the header is absent.
+func ParseIfMatch(r *http.Request) IfMatch {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return nil
+	}
+
+	m := IfMatch{}
+	for _, value := range values {
+		for _, etag := range strings.Split(value, ",") {
+			if etag = strings.TrimSpace(etag); etag != "" {
+				m = append(m, etag)
+			}
+		}
+	}
+	return m
+}
+
+// Present returns true if the request had an If-Match header.
+func (m IfMatch) Present() bool {
+	return m != nil
+}
+
+// Matches returns true if the current entity tag of the resource matches the
+// header using the strong comparison function. If the header is absent, true
+// is returned. An empty etag indicates that the resource does not exist, which
+// never matches.
+func (m IfMatch) Matches(etag string) bool {
+	if !m.Present() {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	return etagListMatches(strings.Join(m, ","), quoteETag(etag), false)
+}
+
+// Check returns a PreconditionFailedError if the header does not match the
+// given current entity tag.
+func (m IfMatch) Check(etag string) error {
+	if !m.Matches(etag) {
+		return PreconditionFailed(etag)
+	}
+	return nil
+}
+
+// PreconditionFailedError is returned by handlers when a request's
+// precondition does not hold, e.g. because the resource was modified since the
+// client last fetched it. It maps to 412 Precondition Failed.
+type PreconditionFailedError struct {
+	// ETag is the current entity tag of the resource, if any. It is written
+	// as the ETag header of the response so that clients can retry.
+	ETag string
+}
+
+var _ HTTPError = PreconditionFailedError{}
+
+// PreconditionFailed returns a new PreconditionFailedError with the current
+// entity tag of the resource.
+func PreconditionFailed(currentETag string) error {
+	return PreconditionFailedError{ETag: currentETag}
+}
+
+// HTTPStatus implements HTTPError.
+func (e PreconditionFailedError) HTTPStatus() int {
+	return http.StatusPreconditionFailed
+}
+
+// Error implements error.
+func (e PreconditionFailedError) Error() string {
+	return fmt.Sprintf("%d: precondition failed", http.StatusPreconditionFailed)
+}
+
+// RequireIfMatch creates a middleware that rejects requests without an
+// If-Match header with 428 Precondition Required. It should be used on PUT,
+// PATCH and DELETE routes to prevent lost updates.
+func RequireIfMatch() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return ifMatchRequiredHandler{next}
+	}
+}
+
+type ifMatchRequiredHandler struct{ next http.Handler }
+
+var _ RouteAnnotator = ifMatchRequiredHandler{}
+
+func (h ifMatchRequiredHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") == "" {
+		OptsFromContext(r.Context()).ErrorWriter.WriteError(w,
+			WrapHTTPError(http.StatusPreconditionRequired, errors.New("If-Match header required")))
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func (h ifMatchRequiredHandler) AnnotateRoute(ri *RouteInfo) {
+	ri.RequiresIfMatch = true
+}
+
+func (h ifMatchRequiredHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+// setIfMatchFields sets all top-level IfMatch fields of the struct pointed to
+// by v.
+func setIfMatchFields(r *http.Request, v any) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	var ifMatch reflect.Value
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Type != ifMatchType || !rv.Type().Field(i).IsExported() {
+			continue
+		}
+		if !ifMatch.IsValid() {
+			ifMatch = reflect.ValueOf(ParseIfMatch(r))
+		}
+		rv.Field(i).Set(ifMatch)
+	}
+}
+
+// setPreconditionHeaders sets the ETag header of the response if err is a
+// PreconditionFailedError with the current entity tag.
+func setPreconditionHeaders(w http.ResponseWriter, err error) {
+	var pf PreconditionFailedError
+	if errors.As(err, &pf) && pf.ETag != "" {
+		w.Header().Set("ETag", quoteETag(pf.ETag))
+	}
+}
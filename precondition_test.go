@@ -0,0 +1,78 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type updateRequest struct {
+	Name    string  `json:"name"`
+	IfMatch IfMatch `json:"-"`
+}
+
+func TestIfMatch(t *testing.T) {
+	current := versionedResponse{Name: "old", Version: "v2"}
+
+	r := NewRouter(DefaultOpts)
+	r.With(RequireIfMatch()).Put("/strict", Wrap(func(ctx context.Context, req updateRequest) (versionedResponse, error) {
+		return current, nil
+	}))
+	r.Put("/lenient", Wrap(func(ctx context.Context, req updateRequest) (versionedResponse, error) {
+		if err := req.IfMatch.Check(current.ETag()); err != nil {
+			return versionedResponse{}, err
+		}
+		return versionedResponse{Name: req.Name, Version: "v3"}, nil
+	}))
+
+	tests := []struct {
+		name    string
+		path    string
+		ifMatch string
+		status  int
+		body    string
+		etag    string
+	}{
+		{"required", "/strict", "", 428, `{"error":"428: If-Match header required"}`, ""},
+		{"absent", "/lenient", "", 200, `{"name":"new"}`, ""},
+		{"match", "/lenient", `"v1", "v2"`, 200, `{"name":"new"}`, ""},
+		{"wildcard", "/lenient", `*`, 200, `{"name":"new"}`, ""},
+		{"mismatch", "/lenient", `"v1"`, 412, `{"error":"412: precondition failed"}`, `"v2"`},
+		{"weak", "/lenient", `W/"v2"`, 412, `{"error":"412: precondition failed"}`, `"v2"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", test.path, strings.NewReader(`{"name":"new"}`))
+			if test.ifMatch != "" {
+				req.Header.Set("If-Match", test.ifMatch)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.body {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.body, body)
+			}
+			if etag := rec.Header().Get("ETag"); etag != test.etag {
+				t.Errorf("unexpected ETag: expected %q, got %q", test.etag, etag)
+			}
+		})
+	}
+
+	routes, err := Routes(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range routes {
+		if expect := route.Pattern == "/strict"; route.RequiresIfMatch != expect {
+			t.Errorf("route %s: expected RequiresIfMatch=%v", route.Pattern, expect)
+		}
+	}
+}
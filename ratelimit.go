@@ -0,0 +1,255 @@
+package hrt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+)
+
+// RateLimit describes a rate limit: at most Limit requests per Period, with
+// bursts of up to Burst requests.
+type RateLimit struct {
+	// Limit is the number of requests allowed per Period.
+	Limit int
+	// Period is the period over which Limit requests are allowed.
+	Period time.Duration
+	// Burst is the number of requests that may be made at once. If zero, Limit
+	// is used.
+	Burst int
+}
+
+// emission returns the interval between requests at the limited rate.
+func (l RateLimit) emission() time.Duration {
+	return l.Period / time.Duration(l.Limit)
+}
+
+// validate returns an error if the limit cannot be enforced, including when
+// Limit is so large relative to Period that requests would be emitted less
+// than a nanosecond apart.
+func (l RateLimit) validate() error {
+	switch {
+	case l.Limit <= 0:
+		return errors.New("limit must be positive")
+	case l.Period <= 0:
+		return errors.New("period must be positive")
+	case l.emission() <= 0:
+		return fmt.Errorf("limit %d is too large for period %s", l.Limit, l.Period)
+	case l.Burst < 0:
+		return errors.New("burst must not be negative")
+	}
+	return nil
+}
+
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Limit
+}
+
+// RateLimitResult is the result of taking a token from a RateLimitStore.
+type RateLimitResult struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+	// Remaining is the number of requests that can still be made right now.
+	Remaining int
+	// Reset is the duration until the limit is fully replenished.
+	Reset time.Duration
+	// RetryAfter is the duration until the next request is allowed. It is zero
+	// if the request is allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore stores the state of rate limits. Implementations must be safe
+// for concurrent use. A shared backend can implement this interface to share
+// limits between multiple instances.
+type RateLimitStore interface {
+	// Take attempts to take a token for the given key under the given limit.
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// NewMemoryRateLimitStore creates a new in-process RateLimitStore that uses
+// the generic cell rate algorithm (GCRA).
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{tats: make(map[string]time.Time)}
+}
+
+type memoryRateLimitStore struct {
+	mu    sync.Mutex
+	tats  map[string]time.Time // theoretical arrival times
+	takes int
+}
+
+// sweepInterval is the number of takes between each sweep of expired keys.
+const sweepInterval = 1024
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	if err := limit.validate(); err != nil {
+		return RateLimitResult{}, errors.Wrap(err, "invalid rate limit")
+	}
+
+	emission := limit.emission()
+	tolerance := emission * time.Duration(limit.burst())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepInterval == 0 {
+		for k, tat := range s.tats {
+			if tat.Before(now) {
+				delete(s.tats, k)
+			}
+		}
+	}
+
+	tat, ok := s.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(emission)
+	allowAt := newTAT.Add(-tolerance)
+
+	if now.Before(allowAt) {
+		return RateLimitResult{
+			Allowed:    false,
+			Remaining:  0,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, nil
+	}
+
+	s.tats[key] = newTAT
+
+	return RateLimitResult{
+		Allowed:   true,
+		Remaining: int((tolerance - newTAT.Sub(now)) / emission),
+		Reset:     newTAT.Sub(now),
+	}, nil
+}
+
+// RateLimitKeyFunc returns the key that a request is rate limited by. An empty
+// key exempts the request from rate limiting.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP keys requests by the client's IP address as reported in
+// RemoteAddr. Use a middleware that rewrites RemoteAddr if the server is behind
+// a proxy.
+func RateLimitByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimitByHeader keys requests by the value of the given header, such as an
+// API key header.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimitByPrincipal keys requests by the subject of their Principal,
+// falling back to the client IP for unauthenticated requests.
+func RateLimitByPrincipal(r *http.Request) string {
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		return "principal:" + p.Subject()
+	}
+	return RateLimitByIP(r)
+}
+
+// RateLimiter creates rate limiting middlewares that share a store and a
+// key function.
+type RateLimiter struct {
+	// Store stores the rate limit state.
+	Store RateLimitStore
+	// Key returns the key of each request.
+	Key RateLimitKeyFunc
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter. If store is nil, then an
+// in-process store is used.
+func NewRateLimiter(store RateLimitStore, key RateLimitKeyFunc) *RateLimiter {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	return &RateLimiter{Store: store, Key: key}
+}
+
+// Limit creates a middleware that limits requests to the given rate. Limits
+// are tracked separately for each route, so the same limiter can be used with
+// different limits on different routes:
+//
+//	limiter := hrt.NewRateLimiter(nil, hrt.RateLimitByIP)
+//	r.With(limiter.Limit(hrt.RateLimit{Limit: 10, Period: time.Minute})).Post("/login", ...)
+//
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers are set
+// on every response, with RateLimit-Limit being the Limit of the given rate.
+// Rejected requests receive a 429 error written using the ErrorWriter in the
+// request's Opts along with a Retry-After header.
+//
+// Limit panics if the rate is invalid, such as a Limit that is not positive or
+// that is larger than the number of nanoseconds in Period.
+func (l *RateLimiter) Limit(limit RateLimit) func(http.Handler) http.Handler {
+	if err := limit.validate(); err != nil {
+		panic(fmt.Sprintf("hrt: invalid rate limit %+v: %v", limit, err))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := l.Key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				key = r.Method + " " + rctx.RoutePattern() + " " + key
+			}
+
+			now := time.Now()
+			if l.Now != nil {
+				now = l.Now()
+			}
+
+			res, err := l.Store.Take(r.Context(), key, limit, now)
+			if err != nil {
+				OptsFromContext(r.Context()).ErrorWriter.WriteError(w,
+					WrapHTTPError(http.StatusInternalServerError, errors.Wrap(err, "rate limit")))
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				OptsFromContext(r.Context()).ErrorWriter.WriteError(w, NewHTTPError(
+					http.StatusTooManyRequests,
+					fmt.Sprintf("rate limit exceeded, retry in %s", ceilSeconds(res.RetryAfter)+"s")))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
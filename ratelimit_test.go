@@ -0,0 +1,103 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	limiter := NewRateLimiter(nil, RateLimitByIP)
+	limiter.Now = func() time.Time { return now }
+
+	r := NewRouter(DefaultOpts)
+	r.With(limiter.Limit(RateLimit{Limit: 2, Period: time.Second})).Get("/a", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+	r.With(limiter.Limit(RateLimit{Limit: 1, Period: time.Second})).Get("/b", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+	r.With(limiter.Limit(RateLimit{Limit: 1, Period: time.Second, Burst: 3})).Get("/c", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	}))
+
+	type response struct {
+		status     int
+		limit      string
+		remaining  string
+		retryAfter string
+	}
+
+	do := func(path, addr string) response {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return response{
+			status:     rec.Code,
+			limit:      rec.Header().Get("RateLimit-Limit"),
+			remaining:  rec.Header().Get("RateLimit-Remaining"),
+			retryAfter: rec.Header().Get("Retry-After"),
+		}
+	}
+
+	steps := []struct {
+		name   string
+		path   string
+		addr   string
+		after  time.Duration
+		expect response
+	}{
+		{"first", "/a", "1.1.1.1:1", 0, response{200, "2", "1", ""}},
+		{"second", "/a", "1.1.1.1:2", 0, response{200, "2", "0", ""}},
+		{"limited", "/a", "1.1.1.1:3", 0, response{429, "2", "0", "1"}},
+		{"other client", "/a", "2.2.2.2:1", 0, response{200, "2", "1", ""}},
+		{"other route", "/b", "1.1.1.1:1", 0, response{200, "1", "0", ""}},
+		{"burst", "/c", "1.1.1.1:1", 0, response{200, "1", "2", ""}},
+		{"replenished", "/a", "1.1.1.1:1", 500 * time.Millisecond, response{200, "2", "0", ""}},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.after)
+		if got := do(step.path, step.addr); got != step.expect {
+			t.Errorf("%s: expected %+v, got %+v", step.name, step.expect, got)
+		}
+	}
+}
+
+func TestRateLimiter_invalid(t *testing.T) {
+	limits := []RateLimit{
+		{Limit: 0, Period: time.Second},
+		{Limit: 1, Period: 0},
+		{Limit: 2, Period: time.Nanosecond},
+	}
+
+	limiter := NewRateLimiter(nil, RateLimitByIP)
+	for _, limit := range limits {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%+v: expected panic", limit)
+				}
+			}()
+			limiter.Limit(limit)
+		}()
+
+		_, err := limiter.Store.Take(context.Background(), "key", limit, time.Now())
+		if err == nil {
+			t.Errorf("%+v: expected error from store", limit)
+		}
+	}
+}
+
+func TestRateLimitByHeader(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", "key")
+
+	if key := RateLimitByHeader("X-API-Key")(req); key != "key" {
+		t.Errorf("unexpected key %q", key)
+	}
+}
@@ -0,0 +1,248 @@
+package hrt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ServiceRoutes may be implemented by services passed to Register to declare
+// the route of each method explicitly instead of deriving it from the method
+// name. Routes maps method names to routes in the form "METHOD /pattern", e.g.
+// "GET /users/{id}". A route of "-" excludes the method.
+type ServiceRoutes interface {
+	Routes() map[string]string
+}
+
+// routeVerbs maps method name prefixes to HTTP methods. Longer prefixes must
+// come first.
+var routeVerbs = []struct {
+	prefix string
+	method string
+}{
+	{"Create", http.MethodPost},
+	{"Update", http.MethodPut},
+	{"Replace", http.MethodPut},
+	{"Delete", http.MethodDelete},
+	{"Remove", http.MethodDelete},
+	{"Patch", http.MethodPatch},
+	{"List", http.MethodGet},
+	{"Get", http.MethodGet},
+	{"Post", http.MethodPost},
+	{"Put", http.MethodPut},
+}
+
+// Register registers the methods of svc that have the signature
+//
+//	func(ctx context.Context, req RequestT) (ResponseT, error)
+//
+// as handlers on r. Each method is served exactly like a handler created with
+// Wrap and can be introspected the same way, which allows related endpoints to
+// be grouped behind one type and tested without HTTP.
+//
+// Unless svc implements ServiceRoutes, routes are derived from the method
+// names: the leading verb determines the HTTP method, the rest of the name is
+// converted to kebab-case to form the path, and each `url` tagged field of the
+// request type is appended as a path parameter. Methods without a known verb
+// are ignored. For example:
+//
+//	GetUser(ctx, GetUserRequest{ID int `url:"id"`})  -> GET /user/{id}
+//	ListUsers(ctx, ListUsersRequest)                 -> GET /users
+//	CreateUser(ctx, CreateUserRequest)               -> POST /user
+//	DeleteUser(ctx, DeleteUserRequest{ID `url:"id"`}) -> DELETE /user/{id}
+//
+// The recognized verbs are Create, Post (POST), Update, Replace, Put (PUT),
+// Patch (PATCH), Delete, Remove (DELETE), Get and List (GET).
+//
+// Each route is named after its method, so that URLFor(r, "GetUser", req)
+// builds the URL of the GetUser method.
+//
+// Register panics if a route in ServiceRoutes is malformed or refers to a
+// method with an invalid signature.
+func Register(r Router, svc any) {
+	for _, route := range serviceRoutes(svc) {
+		r.Method(route.method, route.pattern, Named(route.name, route.handler))
+	}
+}
+
+type serviceRoute struct {
+	name    string
+	method  string
+	pattern string
+	handler methodHandler
+}
+
+func serviceRoutes(svc any) []serviceRoute {
+	rv := reflect.ValueOf(svc)
+	rt := rv.Type()
+
+	var table map[string]string
+	if t, ok := svc.(ServiceRoutes); ok {
+		table = t.Routes()
+		for name := range table {
+			if _, ok := rt.MethodByName(name); !ok {
+				panic(fmt.Sprintf("hrt: route table of %s refers to unknown method %s", rt, name))
+			}
+		}
+	}
+
+	var routes []serviceRoute
+	for i := 0; i < rt.NumMethod(); i++ {
+		m := rt.Method(i)
+
+		h, ok := newMethodHandler(rv.Method(i))
+		if !ok {
+			if _, listed := table[m.Name]; listed && table[m.Name] != "-" {
+				panic(fmt.Sprintf("hrt: method %s.%s has an invalid handler signature", rt, m.Name))
+			}
+			continue
+		}
+
+		var method, pattern string
+		if table != nil {
+			route, ok := table[m.Name]
+			if !ok || route == "-" {
+				continue
+			}
+			method, pattern, ok = strings.Cut(route, " ")
+			if !ok || !strings.HasPrefix(pattern, "/") {
+				panic(fmt.Sprintf("hrt: invalid route %q for method %s.%s", route, rt, m.Name))
+			}
+		} else {
+			method, pattern, ok = deriveRoute(m.Name, h.reqType)
+			if !ok {
+				continue
+			}
+		}
+
+		mustValidateRequestType(h.reqType)
+
+		routes = append(routes, serviceRoute{
+			name:    m.Name,
+			method:  method,
+			pattern: pattern,
+			handler: h,
+		})
+	}
+
+	return routes
+}
+
+// deriveRoute derives the route of a method from its name and request type.
+func deriveRoute(name string, reqType reflect.Type) (method, pattern string, ok bool) {
+	for _, verb := range routeVerbs {
+		rest := strings.TrimPrefix(name, verb.prefix)
+		if rest == name || rest == "" || !unicode.IsUpper(rune(rest[0])) {
+			continue
+		}
+
+		pattern = "/" + kebabCase(rest)
+		for _, param := range urlParamsOf(reqType) {
+			pattern += "/{" + param + "}"
+		}
+
+		return verb.method, pattern, true
+	}
+	return "", "", false
+}
+
+// urlParamsOf returns the `url` tags of the given struct type.
+func urlParamsOf(rt reflect.Type) []string {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []string
+	for i := 0; i < rt.NumField(); i++ {
+		rft := rt.Field(i)
+		if rft.Anonymous && rft.Tag == "" {
+			params = append(params, urlParamsOf(rft.Type)...)
+			continue
+		}
+		if tag := rft.Tag.Get("url"); tag != "" && rft.IsExported() {
+			params = append(params, tag)
+		}
+	}
+	return params
+}
+
+// kebabCase converts a Go identifier to kebab-case, e.g. UserProfile to
+// user-profile and HTTPServer to http-server.
+func kebabCase(s string) string {
+	runes := []rune(s)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// methodHandler is the reflection-based equivalent of Handler for methods
+// registered using Register.
+type methodHandler struct {
+	fn       reflect.Value
+	reqType  reflect.Type
+	respType reflect.Type
+}
+
+func newMethodHandler(fn reflect.Value) (methodHandler, bool) {
+	ft := fn.Type()
+	if ft.NumIn() != 2 || ft.NumOut() != 2 ||
+		ft.In(0) != contextType || ft.Out(1) != errorType {
+		return methodHandler{}, false
+	}
+
+	return methodHandler{
+		fn:       fn,
+		reqType:  ft.In(1),
+		respType: ft.Out(0),
+	}, true
+}
+
+// ServeHTTP implements http.Handler.
+func (h methodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, func(ctx context.Context, r *http.Request, opts Opts) (any, error) {
+		req, err := decodeRequestValue(h.reqType, httpRequestDecoder(r, opts))
+		if err != nil {
+			return nil, WrapHTTPError(http.StatusBadRequest, err)
+		}
+		if !req.IsValid() {
+			req = reflect.Zero(h.reqType)
+		}
+
+		out := h.fn.Call([]reflect.Value{reflect.ValueOf(ctx), req})
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	})
+}
+
+// Introspect returns information about the handler.
+func (h methodHandler) Introspect() HandlerIntrospection {
+	return HandlerIntrospection{
+		FuncType:     h.fn.Type(),
+		RequestType:  h.reqType,
+		ResponseType: h.respType,
+	}
+}
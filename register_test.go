@@ -0,0 +1,168 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type userService struct{}
+
+type getUserRequest struct {
+	ID int `url:"id"`
+}
+
+type userResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (userService) GetUser(ctx context.Context, req getUserRequest) (userResponse, error) {
+	if req.ID == 0 {
+		return userResponse{}, NewHTTPError(404, "user not found")
+	}
+	return userResponse{ID: req.ID, Name: "user"}, nil
+}
+
+func (userService) ListUsers(ctx context.Context, req None) ([]userResponse, error) {
+	return []userResponse{{ID: 1, Name: "user"}}, nil
+}
+
+func (userService) CreateUser(ctx context.Context, req *userResponse) (userResponse, error) {
+	req.ID = 2
+	return *req, nil
+}
+
+func (userService) DeleteUserProfile(ctx context.Context, req getUserRequest) (None, error) {
+	return Empty, nil
+}
+
+// Neither of these are registered.
+func (userService) Reset()                                                {}
+func (userService) Summary(ctx context.Context, req None) (string, error) { return "", nil }
+
+type tableService struct{ userService }
+
+func (tableService) Routes() map[string]string {
+	return map[string]string{
+		"GetUser":   "GET /users/{id}",
+		"ListUsers": "-",
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name   string
+		svc    any
+		routes []string
+	}{
+		{
+			name: "derived",
+			svc:  userService{},
+			routes: []string{
+				"POST /user",
+				"DELETE /user-profile/{id}",
+				"GET /user/{id}",
+				"GET /users",
+			},
+		},
+		{
+			name:   "table",
+			svc:    tableService{},
+			routes: []string{"GET /users/{id}"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewRouter(DefaultOpts)
+			Register(r, test.svc)
+
+			infos, err := Routes(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var routes []string
+			for _, info := range infos {
+				routes = append(routes, info.Method+" "+info.Pattern)
+				if _, ok := TryIntrospectingHandler(info.Handler); !ok {
+					t.Errorf("route %s %s cannot be introspected", info.Method, info.Pattern)
+				}
+			}
+
+			if strings.Join(routes, "\n") != strings.Join(test.routes, "\n") {
+				t.Errorf("unexpected routes:\n expected: %q\n got: %q", test.routes, routes)
+			}
+		})
+	}
+}
+
+func TestRegister_serve(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	Register(r, userService{})
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		expect string
+	}{
+		{"GET", "/user/5", "", 200, `{"id":5,"name":"user"}`},
+		{"GET", "/user/0", "", 404, `{"error":"404: user not found"}`},
+		{"GET", "/user/abc", "", 400, ""},
+		{"GET", "/users", "", 200, `[{"id":1,"name":"user"}]`},
+		{"POST", "/user", `{"name":"new"}`, 200, `{"id":2,"name":"new"}`},
+		{"DELETE", "/user-profile/5", "{}", 200, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", test.method, test.path, test.status, rec.Code, rec.Body)
+			continue
+		}
+
+		if test.status == 400 {
+			continue
+		}
+
+		if body := strings.TrimSpace(rec.Body.String()); body != test.expect {
+			t.Errorf("%s %s: unexpected body:\n expected: %s\n got: %s", test.method, test.path, test.expect, body)
+		}
+	}
+}
+
+type badTableService struct{ userService }
+
+func (badTableService) Routes() map[string]string {
+	return map[string]string{"Reset": "POST /reset"}
+}
+
+func TestRegister_invalidTable(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	Register(NewRouter(DefaultOpts), badTableService{})
+}
+
+func TestKebabCase(t *testing.T) {
+	tests := map[string]string{
+		"User":        "user",
+		"UserProfile": "user-profile",
+		"HTTPServer":  "http-server",
+		"UserID":      "user-id",
+	}
+	for in, expect := range tests {
+		if got := kebabCase(in); got != expect {
+			t.Errorf("kebabCase(%q): expected %q, got %q", in, expect, got)
+		}
+	}
+}
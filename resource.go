@@ -0,0 +1,144 @@
+package hrt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+// ErrNotFound is returned by a Resource if the requested item does not exist.
+// It is written as a 404 Not Found response.
+var ErrNotFound = NewHTTPError(http.StatusNotFound, "not found")
+
+// Resource describes a collection of items of type T identified by values of
+// type ID. It is served over HTTP by Mount.
+type Resource[ID, T any] interface {
+	// List returns a page of items.
+	List(ctx context.Context, page PageRequest) (Page[T], error)
+	// Get returns the item with the given ID.
+	Get(ctx context.Context, id ID) (T, error)
+	// Create creates a new item and returns it.
+	Create(ctx context.Context, item T) (T, error)
+	// Update replaces the item with the given ID and returns it.
+	Update(ctx context.Context, id ID, item T) (T, error)
+	// Patch applies a partial update to the item with the given ID and
+	// returns it. Implementations usually call patch.Apply on the stored item.
+	Patch(ctx context.Context, id ID, patch Patch) (T, error)
+	// Delete deletes the item with the given ID.
+	Delete(ctx context.Context, id ID) error
+}
+
+// Identifiable may be implemented by items of a Resource to report their ID.
+// Mount uses it to set the Location header of 201 Created responses.
+type Identifiable[ID any] interface {
+	ResourceID() ID
+}
+
+// Mount registers the conventional REST routes of res on r:
+//
+//	GET    {pattern}       List, with PageRequest query parameters
+//	POST   {pattern}       Create, responding with 201 Created
+//	GET    {pattern}/{id}  Get
+//	PUT    {pattern}/{id}  Update
+//	PATCH  {pattern}/{id}  Patch
+//	DELETE {pattern}/{id}  Delete, responding with 204 No Content
+//
+// The {id} parameter is parsed into ID like a `url` tagged field. IDs that
+// cannot be parsed result in 404 Not Found, as does returning ErrNotFound.
+// Each route is a regular Handler and can therefore be introspected.
+func Mount[ID, T any](r Router, pattern string, res Resource[ID, T]) {
+	item := pattern + "/{id}"
+
+	r.Get(pattern, Wrap(func(ctx context.Context, page PageRequest) (Page[T], error) {
+		return res.List(ctx, page)
+	}))
+
+	r.Post(pattern, Wrap(func(ctx context.Context, v T) (Response[T], error) {
+		created, err := res.Create(ctx, v)
+		if err != nil {
+			return Response[T]{}, err
+		}
+
+		resp := Response[T]{Status: http.StatusCreated, Body: created}
+		if ider, ok := any(created).(Identifiable[ID]); ok {
+			r := RequestFromContext(ctx)
+			resp.Header = http.Header{
+				"Location": {itemLocation(r.URL.EscapedPath(), fmt.Sprint(ider.ResourceID()))},
+			}
+		}
+		return resp, nil
+	}))
+
+	r.Get(item, Wrap(func(ctx context.Context, _ None) (T, error) {
+		id, err := resourceID[ID](ctx)
+		if err != nil {
+			var z T
+			return z, err
+		}
+		return res.Get(ctx, id)
+	}))
+
+	r.Put(item, Wrap(func(ctx context.Context, v T) (T, error) {
+		id, err := resourceID[ID](ctx)
+		if err != nil {
+			var z T
+			return z, err
+		}
+		return res.Update(ctx, id, v)
+	}))
+
+	r.Patch(item, Wrap(func(ctx context.Context, patch Patch) (T, error) {
+		id, err := resourceID[ID](ctx)
+		if err != nil {
+			var z T
+			return z, err
+		}
+		return res.Patch(ctx, id, patch)
+	}))
+
+	r.Delete(item, Wrap(func(ctx context.Context, _ None) (Response[None], error) {
+		id, err := resourceID[ID](ctx)
+		if err != nil {
+			return Response[None]{}, err
+		}
+		if err := res.Delete(ctx, id); err != nil {
+			return Response[None]{}, err
+		}
+		return Response[None]{Status: http.StatusNoContent}, nil
+	}))
+}
+
+// itemLocation returns the path of the item with the given ID within the
+// collection at the escaped path collection. The ID is escaped as a single
+// path segment, including the dot segments "." and "..", and the collection
+// path is kept as-is rather than cleaned.
+func itemLocation(collection, id string) string {
+	segment := url.PathEscape(id)
+	if segment == "." || segment == ".." {
+		segment = strings.ReplaceAll(segment, ".", "%2E")
+	}
+	return strings.TrimSuffix(collection, "/") + "/" + segment
+}
+
+// resourceID parses the {id} URL parameter of the current request.
+func resourceID[ID any](ctx context.Context) (ID, error) {
+	var id ID
+
+	s := chi.URLParam(RequestFromContext(ctx), "id")
+	if s == "" {
+		return id, ErrNotFound
+	}
+
+	rv := reflect.ValueOf(&id).Elem()
+	if err := rfutil.SetPrimitiveFromString(rv.Type(), rv, s); err != nil {
+		return id, ErrNotFound
+	}
+
+	return id, nil
+}
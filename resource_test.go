@@ -0,0 +1,223 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type widget struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (w widget) ResourceID() int { return w.ID }
+
+type widgetStore struct {
+	widgets []widget
+	nextID  int
+}
+
+func (s *widgetStore) List(ctx context.Context, page PageRequest) (Page[widget], error) {
+	limit := page.PageLimit(2, 10)
+	end := page.Offset + limit
+	if end > len(s.widgets) {
+		end = len(s.widgets)
+	}
+
+	p := Page[widget]{Items: s.widgets[page.Offset:end]}
+	if end < len(s.widgets) {
+		p.NextOffset = end
+	}
+	return p, nil
+}
+
+func (s *widgetStore) find(id int) (int, error) {
+	for i, w := range s.widgets {
+		if w.ID == id {
+			return i, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (s *widgetStore) Get(ctx context.Context, id int) (widget, error) {
+	i, err := s.find(id)
+	if err != nil {
+		return widget{}, err
+	}
+	return s.widgets[i], nil
+}
+
+func (s *widgetStore) Create(ctx context.Context, w widget) (widget, error) {
+	s.nextID++
+	w.ID = s.nextID
+	s.widgets = append(s.widgets, w)
+	return w, nil
+}
+
+func (s *widgetStore) Update(ctx context.Context, id int, w widget) (widget, error) {
+	i, err := s.find(id)
+	if err != nil {
+		return widget{}, err
+	}
+	w.ID = id
+	s.widgets[i] = w
+	return w, nil
+}
+
+func (s *widgetStore) Patch(ctx context.Context, id int, patch Patch) (widget, error) {
+	i, err := s.find(id)
+	if err != nil {
+		return widget{}, err
+	}
+	w := s.widgets[i]
+	if err := patch.Apply(&w); err != nil {
+		return widget{}, err
+	}
+	w.ID = id
+	s.widgets[i] = w
+	return w, nil
+}
+
+func (s *widgetStore) Delete(ctx context.Context, id int) error {
+	i, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	s.widgets = append(s.widgets[:i], s.widgets[i+1:]...)
+	return nil
+}
+
+func TestMount(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	Mount[int, widget](r, "/widgets", &widgetStore{})
+
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		status   int
+		expect   string
+		location string
+	}{
+		{"POST", "/widgets", `{"name":"a"}`, 201, `{"id":1,"name":"a"}`, "/widgets/1"},
+		{"POST", "/widgets", `{"name":"b"}`, 201, `{"id":2,"name":"b"}`, "/widgets/2"},
+		{"POST", "/widgets", `{"name":"c"}`, 201, `{"id":3,"name":"c"}`, "/widgets/3"},
+		{"GET", "/widgets", "", 200, `{"items":[{"id":1,"name":"a"},{"id":2,"name":"b"}],"next_offset":2}`, ""},
+		{"GET", "/widgets?offset=2", "", 200, `{"items":[{"id":3,"name":"c"}]}`, ""},
+		{"GET", "/widgets/2", "", 200, `{"id":2,"name":"b"}`, ""},
+		{"GET", "/widgets/9", "", 404, `{"error":"404: not found"}`, ""},
+		{"GET", "/widgets/abc", "", 404, `{"error":"404: not found"}`, ""},
+		{"PUT", "/widgets/2", `{"name":"B"}`, 200, `{"id":2,"name":"B"}`, ""},
+		{"PATCH", "/widgets/3", `{"name":"C"}`, 200, `{"id":3,"name":"C"}`, ""},
+		{"DELETE", "/widgets/1", "", 204, "", ""},
+		{"DELETE", "/widgets/1", "", 404, `{"error":"404: not found"}`, ""},
+		{"GET", "/widgets", "", 200, `{"items":[{"id":2,"name":"B"},{"id":3,"name":"C"}]}`, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", test.method, test.path, test.status, rec.Code, rec.Body)
+			continue
+		}
+
+		if body := strings.TrimSpace(rec.Body.String()); body != test.expect {
+			t.Errorf("%s %s: unexpected body:\n expected: %s\n got: %s", test.method, test.path, test.expect, body)
+		}
+
+		if loc := rec.Header().Get("Location"); loc != test.location {
+			t.Errorf("%s %s: expected Location %q, got %q", test.method, test.path, test.location, loc)
+		}
+	}
+}
+
+type note struct {
+	ID string `json:"id"`
+}
+
+func (n note) ResourceID() string { return n.ID }
+
+// noteStore only supports Create, which keeps the given ID.
+type noteStore struct{}
+
+func (noteStore) List(ctx context.Context, page PageRequest) (Page[note], error) {
+	return Page[note]{}, nil
+}
+
+func (noteStore) Get(ctx context.Context, id string) (note, error) {
+	return note{}, ErrNotFound
+}
+
+func (noteStore) Create(ctx context.Context, n note) (note, error) {
+	return n, nil
+}
+
+func (noteStore) Update(ctx context.Context, id string, n note) (note, error) {
+	return note{}, ErrNotFound
+}
+
+func (noteStore) Patch(ctx context.Context, id string, patch Patch) (note, error) {
+	return note{}, ErrNotFound
+}
+
+func (noteStore) Delete(ctx context.Context, id string) error {
+	return ErrNotFound
+}
+
+func TestMount_location(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	r.Route("/users/{user}", func(r Router) {
+		Mount[string, note](r, "/notes", noteStore{})
+	})
+
+	tests := []struct {
+		id     string
+		expect string
+	}{
+		{"a", "/users/x%2Fy/notes/a"},
+		{"a/b?c#d e", "/users/x%2Fy/notes/a%2Fb%3Fc%23d%20e"},
+		{"..", "/users/x%2Fy/notes/%2E%2E"},
+		{".", "/users/x%2Fy/notes/%2E"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/users/x%2Fy/notes", strings.NewReader(`{"id":`+strconv.Quote(test.id)+`}`))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != 201 {
+			t.Errorf("%q: unexpected status %d: %s", test.id, rec.Code, rec.Body)
+			continue
+		}
+		if loc := rec.Header().Get("Location"); loc != test.expect {
+			t.Errorf("%q: expected Location %q, got %q", test.id, test.expect, loc)
+		}
+	}
+}
+
+func TestMount_introspection(t *testing.T) {
+	r := NewRouter(DefaultOpts)
+	Mount[int, widget](r, "/widgets", &widgetStore{})
+
+	routes, err := Routes(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 6 {
+		t.Fatalf("expected 6 routes, got %d", len(routes))
+	}
+
+	for _, route := range routes {
+		if _, ok := route.Introspect(); !ok {
+			t.Errorf("route %s %s cannot be introspected", route.Method, route.Pattern)
+		}
+	}
+}
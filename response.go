@@ -0,0 +1,78 @@
+package hrt
+
+import "net/http"
+
+// Response wraps a handler's response to set its status code and headers.
+// Handlers return it in place of the body, e.g. to respond with 201 Created:
+//
+//	func handleCreate(ctx context.Context, req CreateRequest) (hrt.Response[Item], error) {
+//	    item := create(req)
+//	    return hrt.Response[Item]{Status: http.StatusCreated, Body: item}, nil
+//	}
+//
+// If Status is http.StatusNoContent or Body is None, no body is written.
+type Response[T any] struct {
+	// Status is the status code of the response. If zero, 200 OK is used.
+	Status int
+	// Header contains headers that are added to the response.
+	Header http.Header
+	// Body is the value that is encoded as the response body.
+	Body T
+}
+
+// responseEnvelope is implemented by Response.
+type responseEnvelope interface {
+	envelope() (status int, header http.Header, body any)
+}
+
+func (r Response[T]) envelope() (int, http.Header, any) {
+	return r.Status, r.Header, r.Body
+}
+
+// unwrapResponse applies the status code and headers of a Response onto w and
+// returns its body. ok is false if the response has no body.
+func unwrapResponse(w http.ResponseWriter, e responseEnvelope) (http.ResponseWriter, any, bool) {
+	status, header, body := e.envelope()
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+
+	if _, none := body.(None); none || status == http.StatusNoContent {
+		if status == 0 {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return w, nil, false
+	}
+
+	if status != 0 && status != http.StatusOK {
+		w = &statusWriter{ResponseWriter: w, status: status}
+	}
+	return w, body, true
+}
+
+// statusWriter replaces the implicit 200 OK status of a response with another
+// status code. Other status codes, such as those of errors, are kept.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	if status == http.StatusOK {
+		status = w.status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,160 @@
+package hrt
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouteInfo describes a route registered on a router. It is primarily used for
+// documentation.
+type RouteInfo struct {
+	// Method is the HTTP method of the route.
+	Method string
+	// Pattern is the full chi pattern of the route.
+	Pattern string
+	// Name is the name given to the route using Named or Register. It is used
+	// by URLFor.
+	Name string
+	// Handler is the innermost handler of the route, with all RouteAnnotator
+	// wrappers removed.
+	Handler http.Handler
+	// Scopes is the list of scopes required by the route. All scopes must be
+	// granted to the principal.
+	Scopes []string
+	// Roles is the list of roles required by the route. All roles must be
+	// held by the principal.
+	Roles []string
+	// RequiresIfMatch is true if the route rejects requests without an
+	// If-Match header.
+	RequiresIfMatch bool
+}
+
+// Introspect introspects the route's handler. See TryIntrospectingHandler.
+func (ri RouteInfo) Introspect() (HandlerIntrospection, bool) {
+	return TryIntrospectingHandler(ri.Handler)
+}
+
+// OpenAPISecurity returns the route's required scopes as an OpenAPI security
+// requirement list under the given security scheme name. Nil is returned if
+// the route has no requirements.
+//
+// Roles are not scopes, so they are not listed. A route that only requires
+// roles returns a requirement with no scopes, which still marks the route as
+// requiring authentication; the roles themselves are available in Roles.
+func (ri RouteInfo) OpenAPISecurity(scheme string) []map[string][]string {
+	if len(ri.Scopes) == 0 && len(ri.Roles) == 0 {
+		return nil
+	}
+	scopes := append([]string{}, ri.Scopes...)
+	return []map[string][]string{{scheme: scopes}}
+}
+
+// RouteAnnotator is implemented by handlers that wish to record information
+// about the routes that they serve. Middlewares may return a handler that
+// implements RouteAnnotator to have their information show up in Routes.
+type RouteAnnotator interface {
+	http.Handler
+	// AnnotateRoute records information about the route into ri.
+	AnnotateRoute(ri *RouteInfo)
+	// Unwrap returns the handler that is being wrapped.
+	Unwrap() http.Handler
+}
+
+// Routes walks the given router and returns information about all of its
+// routes, sorted by pattern and method. Middlewares are probed by wrapping a
+// no-op handler and checking if the result implements RouteAnnotator.
+func Routes(r chi.Routes) ([]RouteInfo, error) {
+	var routes []RouteInfo
+	err := chi.Walk(r, func(method, pattern string, h http.Handler, mws ...func(http.Handler) http.Handler) error {
+		ri := RouteInfo{
+			Method:  method,
+			Pattern: pattern,
+		}
+
+		for _, mw := range mws {
+			if a, ok := mw(noopHandler).(RouteAnnotator); ok {
+				a.AnnotateRoute(&ri)
+			}
+		}
+
+		for {
+			a, ok := h.(RouteAnnotator)
+			if !ok {
+				break
+			}
+			a.AnnotateRoute(&ri)
+			h = a.Unwrap()
+		}
+
+		ri.Handler = h
+		routes = append(routes, ri)
+		return nil
+	})
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes, err
+}
+
+var noopHandler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+// patternParams returns the names of the {params} in a chi pattern, without
+// their regular expressions.
+func patternParams(pattern string) []string {
+	var params []string
+	for {
+		p, ok := nextPatternParam(pattern)
+		if !ok {
+			return params
+		}
+		params = append(params, p.name)
+		pattern = pattern[p.end:]
+	}
+}
+
+// patternParam is a {param} within a chi pattern.
+type patternParam struct {
+	// start and end are the offsets of the braces, end being exclusive.
+	start, end int
+	name       string
+	regexp     string
+}
+
+// nextPatternParam returns the first {param} in pattern.
+func nextPatternParam(pattern string) (patternParam, bool) {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return patternParam{}, false
+	}
+
+	// Regular expressions may contain braces themselves, e.g.
+	// {id:[0-9]{3}}, so find the matching closing brace.
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				name, re, _ := strings.Cut(pattern[start+1:i], ":")
+				return patternParam{
+					start:  start,
+					end:    i + 1,
+					name:   strings.TrimSpace(name),
+					regexp: re,
+				}, true
+			}
+		}
+	}
+
+	return patternParam{}, false
+}
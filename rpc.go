@@ -0,0 +1,329 @@
+package hrt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+	// RPCServerError is used for errors with an HTTP status code that has no
+	// equivalent JSON-RPC error code. The HTTP status code is included in the
+	// error's data.
+	RPCServerError = -32000
+)
+
+// RPCHandler describes a handler that can be called using JSON-RPC. Handler
+// implements RPCHandler.
+type RPCHandler interface {
+	// ServeRPC decodes params and calls the handler.
+	ServeRPC(ctx context.Context, params json.RawMessage) (any, error)
+}
+
+var _ RPCHandler = Handler[None, None](nil)
+
+// ServeRPC implements RPCHandler. The params must be a JSON object, which is
+// decoded into RequestT and validated like a regular request.
+func (h Handler[RequestT, ResponseT]) ServeRPC(ctx context.Context, params json.RawMessage) (any, error) {
+	req, err := decodeRequest[RequestT](func(v any) error {
+		params := bytes.TrimSpace(params)
+		if len(params) > 0 && params[0] == '[' {
+			return rpcError{RPCInvalidParams, "params must be an object", nil}
+		}
+
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, v); err != nil {
+				return rpcError{RPCInvalidParams, err.Error(), nil}
+			}
+		}
+		if err := checkEnums(v); err != nil {
+			return toRPCError(err)
+		}
+
+		if validator, ok := v.(Validator); ok {
+			if err := validator.Validate(); err != nil {
+				return rpcError{RPCInvalidParams, err.Error(), nil}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result any = resp
+	if e, ok := result.(responseEnvelope); ok {
+		_, _, result = e.envelope()
+	}
+
+	if _, ok := result.(None); ok {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// DefaultRPCConcurrency is the default maximum number of requests within a
+// JSON-RPC batch that are called at once.
+const DefaultRPCConcurrency = 8
+
+// RPCServer dispatches JSON-RPC 2.0 requests over HTTP to registered handlers.
+// Both single and batch requests are supported, as well as notifications.
+// A handler that panics results in an internal error response.
+//
+// # Example
+//
+//	rpc := hrt.NewRPCServer()
+//	rpc.Register("echo", hrt.Wrap(handleEcho))
+//	r.Post("/rpc", rpc)
+type RPCServer struct {
+	// Concurrency is the maximum number of requests within a batch that are
+	// called at once. If zero, DefaultRPCConcurrency is used.
+	Concurrency int
+
+	methods map[string]RPCHandler
+}
+
+// NewRPCServer creates a new RPCServer.
+func NewRPCServer() *RPCServer {
+	return &RPCServer{methods: make(map[string]RPCHandler)}
+}
+
+// Register registers the given handler under the given method name. The
+// handler must implement RPCHandler, which is the case for handlers created
+// using Wrap. It panics otherwise or if the method name is already taken.
+func (s *RPCServer) Register(method string, h http.Handler) {
+	rpch, ok := h.(RPCHandler)
+	if !ok {
+		panic(fmt.Sprintf("hrt: handler %T for RPC method %q does not implement RPCHandler", h, method))
+	}
+	if _, ok := s.methods[method]; ok {
+		panic(fmt.Sprintf("hrt: RPC method %q registered twice", method))
+	}
+	s.methods[method] = rpch
+}
+
+// Methods returns the introspection of each registered method.
+func (s *RPCServer) Methods() map[string]HandlerIntrospection {
+	methods := make(map[string]HandlerIntrospection, len(s.methods))
+	for name, h := range s.methods {
+		if h, ok := h.(http.Handler); ok {
+			methods[name], _ = TryIntrospectingHandler(h)
+		}
+	}
+	return methods
+}
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e rpcError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+type rpcErrorData struct {
+	Status int `json:"status"`
+}
+
+// ServeHTTP implements http.Handler.
+func (s *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		OptsFromContext(r.Context()).ErrorWriter.WriteError(w,
+			NewHTTPError(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), requestCtxKey, r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeRPCResponse(w, rpcErrorResponse(nil, rpcError{RPCParseError, "failed to read body", nil}))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []json.RawMessage
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			writeRPCResponse(w, rpcErrorResponse(nil, rpcError{RPCParseError, err.Error(), nil}))
+			return
+		}
+		if len(reqs) == 0 {
+			writeRPCResponse(w, rpcErrorResponse(nil, rpcError{RPCInvalidRequest, "empty batch", nil}))
+			return
+		}
+
+		concurrency := s.Concurrency
+		if concurrency <= 0 {
+			concurrency = DefaultRPCConcurrency
+		}
+
+		resps := make([]*rpcResponse, len(reqs))
+		sema := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for i, req := range reqs {
+			sema <- struct{}{}
+			wg.Add(1)
+
+			go func(i int, req json.RawMessage) {
+				defer func() { <-sema }()
+				defer wg.Done()
+				resps[i] = s.call(ctx, req)
+			}(i, req)
+		}
+		wg.Wait()
+
+		var nonNotifications []*rpcResponse
+		for _, resp := range resps {
+			if resp != nil {
+				nonNotifications = append(nonNotifications, resp)
+			}
+		}
+
+		if len(nonNotifications) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		writeRPCResponse(w, nonNotifications)
+		return
+	}
+
+	resp := s.call(ctx, body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeRPCResponse(w, resp)
+}
+
+// call calls a single JSON-RPC request. Nil is returned for notifications.
+func (s *RPCServer) call(ctx context.Context, body json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return rpcErrorResponse(nil, rpcError{RPCParseError, err.Error(), nil})
+		}
+		return rpcErrorResponse(nil, rpcError{RPCInvalidRequest, err.Error(), nil})
+	}
+
+	if req.Version != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, rpcError{RPCInvalidRequest, "invalid request", nil})
+	}
+
+	notification := req.ID == nil
+
+	h, ok := s.methods[req.Method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return rpcErrorResponse(req.ID, rpcError{RPCMethodNotFound, "method not found", nil})
+	}
+
+	result, err := serveRPC(ctx, h, req.Params)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		return rpcErrorResponse(req.ID, toRPCError(err))
+	}
+
+	return &rpcResponse{
+		Version: "2.0",
+		Result:  rpcResult{result},
+		ID:      req.ID,
+	}
+}
+
+// serveRPC calls h, turning a panic into an internal error, since batch
+// requests are called in their own goroutines.
+func serveRPC(ctx context.Context, h RPCHandler, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			result, err = nil, rpcError{RPCInternalError, "internal error", nil}
+		}
+	}()
+	return h.ServeRPC(ctx, params)
+}
+
+// rpcResult ensures that a nil result is still encoded as null, since the
+// result member is required on success.
+type rpcResult struct{ v any }
+
+func (r rpcResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.v)
+}
+
+func rpcErrorResponse(id json.RawMessage, err rpcError) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{
+		Version: "2.0",
+		Error:   &err,
+		ID:      id,
+	}
+}
+
+// toRPCError converts an error returned by a handler into a JSON-RPC error.
+// HTTPError status codes are mapped to the closest JSON-RPC error code.
+func toRPCError(err error) rpcError {
+	var rpcErr rpcError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	status := ErrorHTTPStatus(err, http.StatusInternalServerError)
+	data := rpcErrorData{Status: status}
+
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return rpcError{RPCInvalidParams, err.Error(), data}
+	case status >= 500:
+		return rpcError{RPCInternalError, err.Error(), data}
+	default:
+		return rpcError{RPCServerError, err.Error(), data}
+	}
+}
+
+func writeRPCResponse(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
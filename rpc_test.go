@@ -0,0 +1,148 @@
+package hrt
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRPCServer(t *testing.T) {
+	var notified bool
+
+	rpc := NewRPCServer()
+	rpc.Register("echo", Wrap(func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{What: req.What}, nil
+	}))
+	rpc.Register("notify", Wrap(func(ctx context.Context, req None) (None, error) {
+		notified = true
+		return Empty, nil
+	}))
+	rpc.Register("status", Wrap(func(ctx context.Context, req enumBody) (None, error) {
+		return Empty, nil
+	}))
+	rpc.Register("panic", Wrap(func(ctx context.Context, req None) (None, error) {
+		panic("oops")
+	}))
+	rpc.Register("forbidden", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, NewHTTPError(403, "no")
+	}))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		expect string
+	}{
+		{
+			name:   "call",
+			body:   `{"jsonrpc": "2.0", "method": "echo", "params": {"what": "hi!"}, "id": 1}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","result":{"what":"hi!"},"id":1}`,
+		},
+		{
+			name:   "invalid params",
+			body:   `{"jsonrpc": "2.0", "method": "echo", "params": {"what": "hi"}, "id": "a"}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"enthusiasm required"},"id":"a"}`,
+		},
+		{
+			name:   "positional params",
+			body:   `{"jsonrpc": "2.0", "method": "echo", "params": ["hi!"], "id": 2}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"params must be an object"},"id":2}`,
+		},
+		{
+			name:   "http error",
+			body:   `{"jsonrpc": "2.0", "method": "forbidden", "id": 3}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32000,"message":"403: no","data":{"status":403}},"id":3}`,
+		},
+		{
+			name:   "none result",
+			body:   `{"jsonrpc": "2.0", "method": "notify", "id": 4}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","result":null,"id":4}`,
+		},
+		{
+			name:   "method not found",
+			body:   `{"jsonrpc": "2.0", "method": "nope", "id": 5}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":5}`,
+		},
+		{
+			name:   "invalid version",
+			body:   `{"jsonrpc": "1.0", "method": "echo", "id": 6}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":6}`,
+		},
+		{
+			name:   "parse error",
+			body:   `{"jsonrpc": "2.0", "method"`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`,
+		},
+		{
+			name:   "notification",
+			body:   `{"jsonrpc": "2.0", "method": "notify"}`,
+			status: 204,
+		},
+		{
+			name: "batch",
+			body: `[
+				{"jsonrpc": "2.0", "method": "echo", "params": {"what": "a!"}, "id": 1},
+				{"jsonrpc": "2.0", "method": "notify"},
+				{"jsonrpc": "2.0", "method": "echo", "params": {"what": "b!"}, "id": 2}
+			]`,
+			status: 200,
+			expect: `[{"jsonrpc":"2.0","result":{"what":"a!"},"id":1},{"jsonrpc":"2.0","result":{"what":"b!"},"id":2}]`,
+		},
+		{
+			name:   "invalid enum",
+			body:   `{"jsonrpc": "2.0", "method": "status", "params": {"filter": {"statuses": ["deleted"]}}, "id": 7}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"400: invalid filter.statuses[0] \"deleted\" (expected one of active, archived)","data":{"status":400}},"id":7}`,
+		},
+		{
+			name:   "panic",
+			body:   `{"jsonrpc": "2.0", "method": "panic", "id": 8}`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"internal error"},"id":8}`,
+		},
+		{
+			name: "batch with panic",
+			body: `[
+				{"jsonrpc": "2.0", "method": "panic", "id": 1},
+				{"jsonrpc": "2.0", "method": "echo", "params": {"what": "a!"}, "id": 2}
+			]`,
+			status: 200,
+			expect: `[{"jsonrpc":"2.0","error":{"code":-32603,"message":"internal error"},"id":1},{"jsonrpc":"2.0","result":{"what":"a!"},"id":2}]`,
+		},
+		{
+			name:   "empty batch",
+			body:   `[]`,
+			status: 200,
+			expect: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rpc.ServeHTTP(rec, httptest.NewRequest("POST", "/rpc", strings.NewReader(test.body)))
+
+			if rec.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != test.expect {
+				t.Errorf("unexpected body:\n"+
+					"expected: %s\n"+
+					"got:      %s", test.expect, body)
+			}
+		})
+	}
+
+	if !notified {
+		t.Error("notification was not dispatched")
+	}
+}
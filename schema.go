@@ -0,0 +1,394 @@
+package hrt
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema as used by OpenAPI 3.1. Only the subset of
+// keywords that can be derived from Go types is supported.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Discriminator        *Discriminator     `json:"discriminator,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+}
+
+// Discriminator is the OpenAPI discriminator object of a oneOf schema.
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+// SchemaGenerator generates JSON schemas from Go types. Named struct types are
+// generated once into Schemas and referred to using $ref, which allows
+// recursive types and matches the layout of OpenAPI components.
+//
+// # Example
+//
+//	g := hrt.NewSchemaGenerator()
+//	for _, route := range routes {
+//	    if h, ok := route.Introspect(); ok {
+//	        req := g.Generate(h.RequestType)
+//	        resp := g.Generate(h.ResponseType)
+//	        // ...
+//	    }
+//	}
+//	components := g.Schemas
+type SchemaGenerator struct {
+	// Schemas contains the schemas of named struct types by name.
+	Schemas map[string]*Schema
+	// RefPrefix is prepended to the names of named struct types to form
+	// their $ref. It defaults to "#/components/schemas/".
+	RefPrefix string
+
+	names map[reflect.Type]string
+}
+
+// NewSchemaGenerator creates a new SchemaGenerator.
+func NewSchemaGenerator() *SchemaGenerator {
+	return &SchemaGenerator{
+		Schemas:   make(map[string]*Schema),
+		RefPrefix: "#/components/schemas/",
+		names:     make(map[reflect.Type]string),
+	}
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// schemaProvider may be implemented by types whose schema cannot be derived
+// from their structure, such as Optional and Union.
+type schemaProvider interface {
+	jsonSchema(g *SchemaGenerator) *Schema
+}
+
+// Generate returns the schema of the given type. It returns nil for None.
+func (g *SchemaGenerator) Generate(t reflect.Type) *Schema {
+	if t == nil || t == noneType {
+		return nil
+	}
+
+	// Dereference pointers first, since schemaProviders such as Optional and
+	// Union may not be called on nil pointers.
+	if t.Kind() == reflect.Ptr {
+		return g.Generate(t.Elem())
+	}
+
+	if p, ok := reflect.Zero(t).Interface().(schemaProvider); ok {
+		return p.jsonSchema(g)
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.TypeFor[json.RawMessage]():
+		return &Schema{}
+	}
+
+	if t.Implements(enumType) {
+		s := &Schema{}
+		if t.Kind() == reflect.String {
+			s.Type = "string"
+		}
+		for _, v := range reflect.Zero(t).Interface().(Enum).Enum() {
+			s.Enum = append(s.Enum, v)
+		}
+		return s
+	}
+
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Generate(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// ref returns a $ref schema to the named struct type t, generating it into
+// Schemas if needed.
+func (g *SchemaGenerator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = schemaName(t)
+		for i := 2; g.Schemas[name] != nil; i++ {
+			name = schemaName(t) + "_" + strconv.Itoa(i)
+		}
+		g.names[t] = name
+
+		// Reserve the name before generating to support recursive types.
+		s := &Schema{}
+		g.Schemas[name] = s
+		*s = *g.structSchema(t)
+	}
+	return &Schema{Ref: g.RefPrefix + name}
+}
+
+// component returns the schema of the named struct type t within Schemas.
+func (g *SchemaGenerator) component(t reflect.Type) *Schema {
+	g.ref(t)
+	return g.Schemas[g.names[t]]
+}
+
+func (g *SchemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	g.addStructFields(s, t)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return s
+}
+
+func (g *SchemaGenerator) addStructFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addStructFields(s, ft)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.Generate(f.Type)
+		if def, ok := f.Tag.Lookup("default"); ok && fs != nil {
+			fs = schemaWithDefault(fs, f, def)
+		}
+
+		s.Properties[name] = fs
+		if !hasTagOption(opts, "omitempty") && !hasTagOption(opts, "omitzero") && !isOptionalType(f.Type) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// schemaWithDefault returns a copy of s with the parsed default value. Invalid
+// defaults are omitted.
+func schemaWithDefault(s *Schema, f reflect.StructField, def string) *Schema {
+	policy, err := fieldPolicy(f, ParsePolicy{})
+	if err != nil {
+		return s
+	}
+
+	v, err := parseDefault(f.Type, def, policy.parseOpts())
+	if err != nil {
+		return s
+	}
+
+	if s.Ref != "" {
+		// Siblings of $ref are allowed in OpenAPI 3.1.
+		s = &Schema{Ref: s.Ref}
+	} else {
+		c := *s
+		s = &c
+	}
+
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		v = v.Elem()
+	}
+
+	switch {
+	case t == timeType && s.Type == "integer":
+		// Unix timestamps.
+		s.Default, _ = strconv.ParseInt(def, 10, 64)
+	case t == durationType || t.Implements(textMarshalerType):
+		s.Default = def
+	default:
+		s.Default = v.Interface()
+	}
+	return s
+}
+
+// Parameter is an OpenAPI parameter object describing a path or query
+// parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Parameters returns the parameters of the request type t as decoded by
+// URLDecoder. Fields tagged with `url` are path parameters, and fields tagged
+// with `query`, `form` or `schema` are query parameters. Values are described
+// the way they appear in URLs, e.g. a time.Duration as a string with the
+// "duration" format.
+func (g *SchemaGenerator) Parameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous && f.Tag == "" && f.Type.Kind() == reflect.Struct &&
+			!reflect.PointerTo(f.Type).Implements(textUnmarshalerType) {
+			params = append(params, g.Parameters(f.Type)...)
+			continue
+		}
+
+		if !f.IsExported() || f.Type == ifMatchType {
+			continue
+		}
+
+		for _, tag := range []string{"url", "form", "query", "schema"} {
+			name, _ := parseTag(f.Tag.Get(tag))
+			if name == "" {
+				continue
+			}
+
+			p := Parameter{Name: name, In: "query", Schema: g.paramSchema(f)}
+			if tag == "url" {
+				p.In = "path"
+				p.Required = true
+			}
+			params = append(params, p)
+			break
+		}
+	}
+
+	return params
+}
+
+func (g *SchemaGenerator) paramSchema(f reflect.StructField) *Schema {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	policy, _ := fieldPolicy(f, ParsePolicy{})
+
+	var s *Schema
+	switch {
+	case t == durationType:
+		s = &Schema{Type: "string", Format: "duration"}
+	case t == timeType:
+		switch policy.TimeLayout {
+		case "", time.RFC3339:
+			s = &Schema{Type: "string", Format: "date-time"}
+		case "2006-01-02":
+			s = &Schema{Type: "string", Format: "date"}
+		case TimeLayoutUnix, TimeLayoutUnixMilli:
+			s = &Schema{Type: "integer", Format: "int64"}
+		default:
+			s = &Schema{Type: "string"}
+		}
+	default:
+		s = g.Generate(t)
+	}
+
+	if def, ok := f.Tag.Lookup("default"); ok {
+		s = schemaWithDefault(s, f, def)
+	}
+	return s
+}
+
+func hasTagOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName returns the component name of t. Characters that are not allowed
+// in component names, such as the brackets of generic types, are replaced.
+func schemaName(t reflect.Type) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, t.Name())
+}
+
+func (o Optional[T]) jsonSchema(g *SchemaGenerator) *Schema {
+	return g.Generate(reflect.TypeFor[T]())
+}
+
+func (o Optional[T]) optional() {}
+
+// isOptionalType returns true if t is an Optional, which may be absent and is
+// therefore never required.
+func isOptionalType(t reflect.Type) bool {
+	_, ok := reflect.Zero(t).Interface().(interface{ optional() })
+	return ok
+}
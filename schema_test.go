@@ -0,0 +1,164 @@
+package hrt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaBase struct {
+	Version int `json:"version"`
+}
+
+type schemaRequest struct {
+	schemaBase
+	ID       uint64               `json:"id"`
+	Score    float64              `json:"score,omitempty"`
+	Tags     map[string]string    `json:"tags"`
+	Created  time.Time            `json:"created"`
+	Note     Optional[string]     `json:"note"`
+	Root     *schemaNode          `json:"root"`
+	Method   Union[paymentMethod] `json:"method"`
+	Internal string               `json:"-"`
+	Inline   struct {
+		OK bool `json:"ok"`
+	} `json:"inline"`
+}
+
+func TestSchemaGenerator(t *testing.T) {
+	g := NewSchemaGenerator()
+	s := g.Generate(reflect.TypeFor[schemaRequest]())
+
+	expect := map[string]string{
+		"": `{"$ref":"#/components/schemas/schemaRequest"}`,
+		"schemaRequest": `{
+			"type": "object",
+			"properties": {
+				"version": {"type": "integer", "format": "int64"},
+				"id": {"type": "integer", "minimum": 0},
+				"score": {"type": "number", "format": "double"},
+				"tags": {"type": "object", "additionalProperties": {"type": "string"}},
+				"created": {"type": "string", "format": "date-time"},
+				"note": {"type": "string"},
+				"root": {"$ref": "#/components/schemas/schemaNode"},
+				"method": {
+					"oneOf": [
+						{"$ref": "#/components/schemas/bankPayment"},
+						{"$ref": "#/components/schemas/cardPayment"},
+						{"$ref": "#/components/schemas/cashPayment"}
+					],
+					"discriminator": {
+						"propertyName": "type",
+						"mapping": {
+							"bank": "#/components/schemas/bankPayment",
+							"card": "#/components/schemas/cardPayment",
+							"cash": "#/components/schemas/cashPayment"
+						}
+					}
+				},
+				"inline": {
+					"type": "object",
+					"properties": {"ok": {"type": "boolean"}},
+					"required": ["ok"]
+				}
+			},
+			"required": ["version", "id", "tags", "created", "root", "method", "inline"]
+		}`,
+		"schemaNode": `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"children": {"type": "array", "items": {"$ref": "#/components/schemas/schemaNode"}}
+			},
+			"required": ["name"]
+		}`,
+		"cardPayment": `{
+			"type": "object",
+			"properties": {
+				"type": {"type": "string", "enum": ["card"]},
+				"number": {"type": "string"}
+			},
+			"required": ["type", "number"]
+		}`,
+		"cashPayment": `{
+			"type": "object",
+			"properties": {"type": {"type": "string", "enum": ["cash"]}},
+			"required": ["type"]
+		}`,
+	}
+
+	for name, expect := range expect {
+		got := s
+		if name != "" {
+			got = g.Schemas[name]
+		}
+		assertJSONEqual(t, name, expect, got)
+	}
+
+	if len(g.Schemas) != 5 {
+		t.Errorf("expected 5 schemas, got %d", len(g.Schemas))
+	}
+}
+
+func assertJSONEqual(t *testing.T, name, expect string, got any) {
+	t.Helper()
+
+	var e, g any
+	if err := json.Unmarshal([]byte(expect), &e); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e, g) {
+		eb, _ := json.Marshal(e)
+		t.Errorf("%s: unexpected body:\n expected: %s\n got: %s", name, eb, b)
+	}
+}
+
+func TestSchemaGenerator_pointers(t *testing.T) {
+	type request struct {
+		Note   *Optional[string]     `json:"note"`
+		Method *Union[paymentMethod] `json:"method"`
+		Time   *time.Time            `json:"time"`
+	}
+
+	g := NewSchemaGenerator()
+	g.Generate(reflect.TypeFor[*request]())
+
+	assertJSONEqual(t, "request", `{
+		"type": "object",
+		"properties": {
+			"note": {"type": "string"},
+			"method": {
+				"oneOf": [
+					{"$ref": "#/components/schemas/bankPayment"},
+					{"$ref": "#/components/schemas/cardPayment"},
+					{"$ref": "#/components/schemas/cashPayment"}
+				],
+				"discriminator": {
+					"propertyName": "type",
+					"mapping": {
+						"bank": "#/components/schemas/bankPayment",
+						"card": "#/components/schemas/cardPayment",
+						"cash": "#/components/schemas/cashPayment"
+					}
+				}
+			},
+			"time": {"type": "string", "format": "date-time"}
+		},
+		"required": ["method", "time"]
+	}`, g.Schemas["request"])
+}
@@ -0,0 +1,264 @@
+package hrt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type unionInfo struct {
+	discriminator string
+	types         map[string]reflect.Type
+	names         map[reflect.Type]string
+}
+
+func (u *unionInfo) keys() []string {
+	keys := make([]string, 0, len(u.types))
+	for k := range u.types {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var unions sync.Map // reflect.Type -> *unionInfo
+
+// RegisterUnion registers the variants of the tagged union described by the
+// interface type I. Each variant is identified by the value of the given
+// discriminator field in its JSON object, which is the key of variants. The
+// values of variants are used only for their types; they must encode as JSON
+// objects and must not have a field with the discriminator's name.
+//
+// Fields of type Union[I] are decoded into the variant named by the
+// discriminator and encoded with the discriminator added. RegisterUnion is
+// meant to be called during initialization and panics on invalid input.
+//
+// # Example
+//
+//	type PaymentMethod interface{ isPaymentMethod() }
+//
+//	func init() {
+//	    hrt.RegisterUnion[PaymentMethod]("type", map[string]PaymentMethod{
+//	        "card": CardPayment{},
+//	        "bank": BankPayment{},
+//	    })
+//	}
+//
+//	type ChargeRequest struct {
+//	    Method hrt.Union[PaymentMethod] `json:"method"`
+//	}
+func RegisterUnion[I any](discriminator string, variants map[string]I) {
+	it := reflect.TypeFor[I]()
+	if it.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("hrt: union type %s is not an interface", it))
+	}
+	if discriminator == "" {
+		panic(fmt.Sprintf("hrt: union %s has no discriminator", it))
+	}
+
+	info := &unionInfo{
+		discriminator: discriminator,
+		types:         make(map[string]reflect.Type, len(variants)),
+		names:         make(map[reflect.Type]string, len(variants)),
+	}
+
+	for name, v := range variants {
+		vt := reflect.TypeOf(v)
+		if vt == nil {
+			panic(fmt.Sprintf("hrt: union %s variant %q is nil", it, name))
+		}
+
+		st := vt
+		if st.Kind() == reflect.Ptr {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("hrt: union %s variant %q (%s) is not a struct", it, name, vt))
+		}
+
+		if other, ok := info.names[vt]; ok {
+			panic(fmt.Sprintf("hrt: union %s variants %q and %q have the same type %s", it, name, other, vt))
+		}
+
+		info.types[name] = vt
+		info.names[vt] = name
+	}
+
+	if _, loaded := unions.LoadOrStore(it, info); loaded {
+		panic(fmt.Sprintf("hrt: union %s registered twice", it))
+	}
+}
+
+func lookupUnion(it reflect.Type) (*unionInfo, error) {
+	v, ok := unions.Load(it)
+	if !ok {
+		return nil, fmt.Errorf("union %s is not registered", it)
+	}
+	return v.(*unionInfo), nil
+}
+
+// Union holds a variant of the tagged union described by the interface type I,
+// whose variants are registered using RegisterUnion. The zero value holds no
+// variant and is encoded as null.
+type Union[I any] struct {
+	Value I
+}
+
+// MarshalJSON implements json.Marshaler. The discriminator is added as the
+// first field of the variant's object.
+func (u Union[I]) MarshalJSON() ([]byte, error) {
+	vt := reflect.TypeOf(u.Value)
+	if vt == nil {
+		return []byte("null"), nil
+	}
+
+	info, err := lookupUnion(reflect.TypeFor[I]())
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := info.names[vt]
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a registered variant of %s", vt, reflect.TypeFor[I]())
+	}
+
+	b, err := json.Marshal(u.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '{' {
+		return nil, fmt.Errorf("variant %q of %s is not encoded as an object", name, reflect.TypeFor[I]())
+	}
+
+	k, _ := json.Marshal(info.discriminator)
+	v, _ := json.Marshal(name)
+
+	var buf bytes.Buffer
+	buf.Grow(len(b) + len(k) + len(v) + 2)
+	buf.WriteByte('{')
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+	if rest := bytes.TrimSpace(b[1:]); len(rest) > 0 && rest[0] != '}' {
+		buf.WriteByte(',')
+	}
+	buf.Write(b[1:])
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (u *Union[I]) UnmarshalJSON(b []byte) error {
+	if string(bytes.TrimSpace(b)) == "null" {
+		*u = Union[I]{}
+		return nil
+	}
+
+	info, err := lookupUnion(reflect.TypeFor[I]())
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	raw, ok := fields[info.discriminator]
+	if !ok {
+		return fmt.Errorf("missing discriminator %q", info.discriminator)
+	}
+
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return errors.Wrapf(err, "invalid discriminator %q", info.discriminator)
+	}
+
+	vt, ok := info.types[name]
+	if !ok {
+		return fmt.Errorf("unknown %s %q (expected one of %s)",
+			info.discriminator, name, strings.Join(info.keys(), ", "))
+	}
+
+	var v reflect.Value
+	if vt.Kind() == reflect.Ptr {
+		v = reflect.New(vt.Elem())
+		if err := json.Unmarshal(b, v.Interface()); err != nil {
+			return err
+		}
+	} else {
+		v = reflect.New(vt)
+		if err := json.Unmarshal(b, v.Interface()); err != nil {
+			return err
+		}
+		v = v.Elem()
+	}
+
+	u.Value = v.Interface().(I)
+	return nil
+}
+
+// Validate implements Validator by validating the variant, if it implements
+// Validator.
+func (u Union[I]) Validate() error {
+	if v, ok := any(u.Value).(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func (u Union[I]) jsonSchema(g *SchemaGenerator) *Schema {
+	info, err := lookupUnion(reflect.TypeFor[I]())
+	if err != nil {
+		return &Schema{}
+	}
+
+	s := &Schema{
+		Discriminator: &Discriminator{
+			PropertyName: info.discriminator,
+			Mapping:      make(map[string]string, len(info.types)),
+		},
+	}
+
+	for _, name := range info.keys() {
+		vt := info.types[name]
+		if vt.Kind() == reflect.Ptr {
+			vt = vt.Elem()
+		}
+
+		var ref *Schema
+		if vt.Name() == "" {
+			ref = g.structSchema(vt)
+		} else {
+			ref = g.ref(vt)
+			addDiscriminatorProperty(g.component(vt), info.discriminator, name)
+		}
+
+		s.OneOf = append(s.OneOf, ref)
+		if ref.Ref != "" {
+			s.Discriminator.Mapping[name] = ref.Ref
+		}
+	}
+
+	return s
+}
+
+// addDiscriminatorProperty adds the discriminator property to a variant's
+// schema so that it validates on its own.
+func addDiscriminatorProperty(s *Schema, discriminator, name string) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*Schema)
+	}
+	if _, ok := s.Properties[discriminator]; ok {
+		return
+	}
+	s.Properties[discriminator] = &Schema{Type: "string", Enum: []any{name}}
+	s.Required = append([]string{discriminator}, s.Required...)
+}
@@ -0,0 +1,142 @@
+package hrt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type paymentMethod interface{ isPaymentMethod() }
+
+type cardPayment struct {
+	Number string `json:"number"`
+}
+
+type bankPayment struct {
+	IBAN string `json:"iban"`
+}
+
+type cashPayment struct{}
+
+func (cardPayment) isPaymentMethod()  {}
+func (*bankPayment) isPaymentMethod() {}
+func (cashPayment) isPaymentMethod()  {}
+
+func init() {
+	RegisterUnion[paymentMethod]("type", map[string]paymentMethod{
+		"card": cardPayment{},
+		"bank": &bankPayment{},
+		"cash": cashPayment{},
+	})
+}
+
+type chargeRequest struct {
+	Amount int                  `json:"amount"`
+	Method Union[paymentMethod] `json:"method"`
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect result[paymentMethod]
+		output string
+	}{
+		{
+			name:   "value variant",
+			input:  `{"type": "card", "number": "4242"}`,
+			expect: okResult[paymentMethod](cardPayment{Number: "4242"}),
+			output: `{"type":"card","number":"4242"}`,
+		},
+		{
+			name:   "pointer variant",
+			input:  `{"iban": "DE00", "type": "bank"}`,
+			expect: okResult[paymentMethod](&bankPayment{IBAN: "DE00"}),
+			output: `{"type":"bank","iban":"DE00"}`,
+		},
+		{
+			name:   "empty variant",
+			input:  `{"type": "cash"}`,
+			expect: okResult[paymentMethod](cashPayment{}),
+			output: `{"type":"cash"}`,
+		},
+		{
+			name:   "null",
+			input:  `null`,
+			expect: okResult[paymentMethod](nil),
+			output: `null`,
+		},
+		{
+			name:   "unknown",
+			input:  `{"type": "crypto"}`,
+			expect: result[paymentMethod]{error: `unknown type "crypto" (expected one of bank, card, cash)`},
+		},
+		{
+			name:   "missing discriminator",
+			input:  `{"number": "4242"}`,
+			expect: result[paymentMethod]{error: `missing discriminator "type"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var u Union[paymentMethod]
+			err := json.Unmarshal([]byte(test.input), &u)
+
+			res := combineResult(u.Value, err)
+			if res.error != test.expect.error || !equalItems(res.value, test.expect.value) {
+				t.Fatalf("unexpected result:\n expected: %+v\n got: %+v", test.expect, res)
+			}
+
+			if err != nil {
+				return
+			}
+
+			b, err := json.Marshal(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.output {
+				t.Errorf("unexpected body:\n expected: %s\n got: %s", test.output, b)
+			}
+		})
+	}
+}
+
+func TestUnion_handler(t *testing.T) {
+	h := Wrap(func(ctx context.Context, req chargeRequest) (Union[paymentMethod], error) {
+		if card, ok := req.Method.Value.(cardPayment); ok {
+			card.Number = "****"
+			return Union[paymentMethod]{card}, nil
+		}
+		return req.Method, nil
+	})
+
+	body := `{"amount": 5, "method": {"type": "card", "number": "4242"}}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	expect := `{"type":"card","number":"****"}`
+	if got := strings.TrimSpace(rec.Body.String()); got != expect {
+		t.Errorf("unexpected body:\n expected: %s\n got: %s", expect, got)
+	}
+
+	body = `{"amount": 5, "method": {"type": "crypto"}}`
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	if rec.Code != 400 {
+		t.Errorf("expected status 400, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUnion_unregisteredVariant(t *testing.T) {
+	type otherPayment struct{ cashPayment }
+
+	_, err := json.Marshal(Union[paymentMethod]{otherPayment{}})
+	if err == nil || !strings.Contains(err.Error(), "is not a registered variant") {
+		t.Errorf("expected unregistered variant error, got %v", err)
+	}
+}
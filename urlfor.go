@@ -0,0 +1,185 @@
+package hrt
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+)
+
+// Named names the route served by h, so that URLs to it can be built using
+// URLFor:
+//
+//	r.Get("/users/{id}", hrt.Named("GetUser", hrt.Wrap(handleGetUser)))
+//
+// Routes registered using Register are named after their methods.
+func Named(name string, h http.Handler) http.Handler {
+	return namedHandler{name, h}
+}
+
+type namedHandler struct {
+	name string
+	next http.Handler
+}
+
+var _ RouteAnnotator = namedHandler{}
+
+func (h namedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.next.ServeHTTP(w, r)
+}
+
+func (h namedHandler) AnnotateRoute(ri *RouteInfo) {
+	// The outermost name wins.
+	if ri.Name == "" {
+		ri.Name = h.name
+	}
+}
+
+func (h namedHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+// URLFor builds the URL of the route with the given name from the request
+// value req, which is the reverse of decoding it using URLDecoder:
+//
+//	u, err := hrt.URLFor(r, "GetUser", GetUserRequest{ID: 5})
+//	// u == "/users/5"
+//
+// The {params} of the route's pattern are filled from the same fields that
+// URLDecoder decodes them into: fields tagged with `url`, fields tagged with
+// `json` of the same name, and untagged fields whose names match ignoring
+// case. They must be non-empty and match their regular expressions, if any.
+// They are escaped using url.PathEscape; note that chi does not unescape URL
+// parameters in paths that contain an escaped slash.
+//
+// For GET routes, all other fields are encoded as query parameters like
+// EncodeURLValues does. Requests to other routes are decoded from the body by
+// DefaultEncoder, so their other fields are left out. The returned URL is the
+// path of the route and is relative to wherever r is served.
+//
+// An error is returned if there is no route with the given name, if several
+// routes with different patterns share it, or if req is not the request type
+// of the route's handler. Since URLFor walks r on every call, RouteInfo.URL
+// may be used instead on routes obtained once using Routes.
+func URLFor(r chi.Routes, name string, req any) (string, error) {
+	routes, err := Routes(r)
+	if err != nil {
+		return "", err
+	}
+
+	var found *RouteInfo
+	for i, ri := range routes {
+		if ri.Name != name {
+			continue
+		}
+		if found != nil && found.Pattern != ri.Pattern {
+			return "", fmt.Errorf("route %s is ambiguous: %s and %s", name, found.Pattern, ri.Pattern)
+		}
+		// Prefer the GET route of a pattern that serves several methods,
+		// since it is the one that takes a query.
+		if found == nil || ri.Method == http.MethodGet {
+			found = &routes[i]
+		}
+	}
+	if found == nil {
+		return "", fmt.Errorf("unknown route %s", name)
+	}
+
+	return found.URL(req)
+}
+
+// URL builds the URL of the route from the request value req. See URLFor.
+func (ri RouteInfo) URL(req any) (string, error) {
+	if hi, ok := ri.Introspect(); ok && hi.RequestType != nil {
+		rt, expect := indirectType(reflect.TypeOf(req)), indirectType(hi.RequestType)
+		if rt != expect && !(rt == nil && expect == reflect.TypeOf(None{})) {
+			return "", fmt.Errorf("route %s takes %s, not %v", ri.Pattern, hi.RequestType, rt)
+		}
+	}
+
+	params, query, err := encodeURLRequest(req, ParsePolicy{}, patternParams(ri.Pattern))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot encode request")
+	}
+
+	var b strings.Builder
+	pattern := ri.Pattern
+	for {
+		p, ok := nextPatternParam(pattern)
+		if !ok {
+			break
+		}
+
+		val, ok := params[p.name]
+		if !ok || val == "" {
+			return "", fmt.Errorf("route %s: missing url parameter %q", ri.Pattern, p.name)
+		}
+		delete(params, p.name)
+
+		if p.regexp != "" {
+			re, err := paramRegexp(p.regexp)
+			if err != nil {
+				return "", errors.Wrapf(err, "route %s: invalid pattern for url parameter %q", ri.Pattern, p.name)
+			}
+			if !re.MatchString(val) {
+				return "", fmt.Errorf("route %s: url parameter %q does not match %s: %q", ri.Pattern, p.name, p.regexp, val)
+			}
+		}
+
+		if strings.Contains(pattern[:p.start], "*") {
+			return "", fmt.Errorf("route %s: wildcard patterns are not supported", ri.Pattern)
+		}
+		b.WriteString(pattern[:p.start])
+		b.WriteString(url.PathEscape(val))
+		pattern = pattern[p.end:]
+	}
+	if strings.Contains(pattern, "*") {
+		return "", fmt.Errorf("route %s: wildcard patterns are not supported", ri.Pattern)
+	}
+	b.WriteString(pattern)
+
+	for name := range params {
+		return "", fmt.Errorf("route %s: url parameter %q is not in the pattern", ri.Pattern, name)
+	}
+
+	u := b.String()
+	if ri.Method == http.MethodGet && len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u, nil
+}
+
+var paramRegexps sync.Map // string -> *regexp.Regexp or error
+
+// paramRegexp returns the cached compiled regular expression of a {param}
+// that matches whole values only.
+func paramRegexp(expr string) (*regexp.Regexp, error) {
+	if v, ok := paramRegexps.Load(expr); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		paramRegexps.Store(expr, err)
+		return nil, err
+	}
+
+	paramRegexps.Store(expr, re)
+	return re, nil
+}
+
+func indirectType(rt reflect.Type) reflect.Type {
+	if rt != nil && rt.Kind() == reflect.Ptr {
+		return rt.Elem()
+	}
+	return rt
+}
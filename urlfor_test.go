@@ -0,0 +1,247 @@
+package hrt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestURLFor(t *testing.T) {
+	type listPosts struct {
+		User   string    `url:"user"`
+		Limit  int       `query:"limit" default:"10"`
+		Since  time.Time `query:"since,layout=date"`
+		Tags   []string  `json:"tags"`
+		Drafts bool
+	}
+
+	type getCode struct {
+		Code string `url:"code"`
+	}
+
+	type extraParam struct {
+		ID   int    `url:"id"`
+		Slug string `url:"slug"`
+	}
+
+	type untaggedParam struct {
+		ID string
+	}
+
+	type jsonParam struct {
+		ID   int    `json:"id"`
+		Note string `json:"note"`
+	}
+
+	r := NewRouter(DefaultOpts)
+	Register(r, userService{})
+	r.Get("/users/{user}/posts", Named("ListPosts", Wrap(func(ctx context.Context, req listPosts) (None, error) {
+		return Empty, nil
+	})))
+	r.Route("/codes", func(r Router) {
+		r.Get("/{code:[a-z]{3}}", Named("GetCode", Wrap(func(ctx context.Context, req getCode) (None, error) {
+			return Empty, nil
+		})))
+	})
+	r.Get("/extra/{id}", Named("Extra", Wrap(func(ctx context.Context, req extraParam) (None, error) {
+		return Empty, nil
+	})))
+	r.Get("/a/{id}", Named("Untagged", Wrap(func(ctx context.Context, req untaggedParam) (None, error) {
+		return Empty, nil
+	})))
+	r.Get("/b/{id}", Named("GetJSON", Wrap(func(ctx context.Context, req jsonParam) (None, error) {
+		return Empty, nil
+	})))
+	r.Post("/b/{id}", Named("PostJSON", Wrap(func(ctx context.Context, req jsonParam) (None, error) {
+		return Empty, nil
+	})))
+	r.Get("/health", Named("Health", Wrap(func(ctx context.Context, req None) (None, error) {
+		return Empty, nil
+	})))
+	r.Get("/files/*", Named("Files", http.NotFoundHandler()))
+	r.Get("/a", Named("Twice", http.NotFoundHandler()))
+	r.Get("/b", Named("Twice", http.NotFoundHandler()))
+
+	tests := []struct {
+		name   string
+		route  string
+		req    any
+		expect string
+		error  string
+	}{
+		{
+			name:   "registered",
+			route:  "GetUser",
+			req:    getUserRequest{ID: 5},
+			expect: "/user/5",
+		},
+		{
+			name:   "zero url parameter",
+			route:  "DeleteUserProfile",
+			req:    &getUserRequest{},
+			expect: "/user-profile/0",
+		},
+		{
+			name:   "query",
+			route:  "ListPosts",
+			req:    listPosts{User: "a b/c", Since: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC), Tags: []string{"x"}, Drafts: true},
+			expect: "/users/a%20b%2Fc/posts?Drafts=true&limit=0&since=2021-02-03&tags=%5B%22x%22%5D",
+		},
+		{
+			name:   "regexp",
+			route:  "GetCode",
+			req:    getCode{Code: "abc"},
+			expect: "/codes/abc",
+		},
+		{
+			name:   "untagged url parameter",
+			route:  "Untagged",
+			req:    untaggedParam{ID: "x y"},
+			expect: "/a/x%20y",
+		},
+		{
+			name:   "json url parameter",
+			route:  "GetJSON",
+			req:    jsonParam{ID: 5, Note: "hi"},
+			expect: "/b/5?note=hi",
+		},
+		{
+			name:   "json url parameter of post",
+			route:  "PostJSON",
+			req:    jsonParam{ID: 5, Note: "hi"},
+			expect: "/b/5",
+		},
+		{
+			name:   "none",
+			route:  "Health",
+			req:    nil,
+			expect: "/health",
+		},
+		{
+			name:  "regexp mismatch",
+			route: "GetCode",
+			req:   getCode{Code: "abcd"},
+			error: `route /codes/{code:[a-z]{3}}: url parameter "code" does not match [a-z]{3}: "abcd"`,
+		},
+		{
+			name:  "missing url parameter",
+			route: "ListPosts",
+			req:   listPosts{},
+			error: `route /users/{user}/posts: missing url parameter "user"`,
+		},
+		{
+			name:  "extra url parameter",
+			route: "Extra",
+			req:   extraParam{ID: 1, Slug: "x"},
+			error: `route /extra/{id}: url parameter "slug" is not in the pattern`,
+		},
+		{
+			name:  "wrong type",
+			route: "GetUser",
+			req:   getCode{},
+			error: `route /user/{id} takes hrt.getUserRequest, not hrt.getCode`,
+		},
+		{
+			name:  "unknown route",
+			route: "GetPost",
+			req:   None{},
+			error: `unknown route GetPost`,
+		},
+		{
+			name:  "ambiguous route",
+			route: "Twice",
+			req:   None{},
+			error: `route Twice is ambiguous: /a and /b`,
+		},
+		{
+			name:  "wildcard",
+			route: "Files",
+			req:   None{},
+			error: `route /files/*: wildcard patterns are not supported`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, err := URLFor(r, test.route, test.req)
+			if test.error != "" {
+				if err == nil || err.Error() != test.error {
+					t.Fatalf("unexpected error:\n expected: %s\n got: %v", test.error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if u != test.expect {
+				t.Errorf("unexpected URL:\n expected: %s\n got: %s", test.expect, u)
+			}
+		})
+	}
+}
+
+func TestURLFor_roundTrip(t *testing.T) {
+	type request struct {
+		Org   string `url:"org"`
+		ID    int    `url:"id"`
+		Query string `query:"q"`
+	}
+
+	var got request
+	r := NewRouter(DefaultOpts)
+	r.Get("/orgs/{org}/items/{id:[0-9]+}", Named("GetItem", Wrap(func(ctx context.Context, req request) (None, error) {
+		got = req
+		return Empty, nil
+	})))
+
+	expect := request{Org: "ü x", ID: 42, Query: "a&b"}
+	u, err := URLFor(r, "GetItem", expect)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d for %s: %s", w.Code, u, w.Body)
+	}
+	if got != expect {
+		t.Errorf("unexpected request:\n expected: %+v\n got: %+v", expect, got)
+	}
+}
+
+func TestURLFor_roundTripUntagged(t *testing.T) {
+	type request struct {
+		Org   string
+		ID    int    `json:"id"`
+		Query string `json:"q"`
+	}
+
+	var got request
+	r := NewRouter(DefaultOpts)
+	r.Get("/orgs/{org}/items/{id}", Named("GetItem", Wrap(func(ctx context.Context, req request) (None, error) {
+		got = req
+		return Empty, nil
+	})))
+
+	if issues := Lint(r); len(issues) > 0 {
+		t.Fatalf("unexpected lint issues: %v", issues)
+	}
+
+	expect := request{Org: "ü x", ID: 42, Query: "a&b"}
+	u, err := URLFor(r, "GetItem", expect)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d for %s: %s", w.Code, u, w.Body)
+	}
+	if got != expect {
+		t.Errorf("unexpected request:\n expected: %+v\n got: %+v", expect, got)
+	}
+}
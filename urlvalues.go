@@ -0,0 +1,143 @@
+package hrt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"libdb.so/hrt/v2/internal/rfutil"
+)
+
+// EncodeURLValues encodes the fields of the request value v into query
+// parameters. It is the reverse of URLDecoder with the default ParsePolicy and
+// uses the same tags and tag options, so that clients build requests that the
+// server decodes into the same value:
+//
+//	q, err := hrt.EncodeURLValues(ListRequest{Limit: 10, Since: since})
+//	u := "/items?" + q.Encode()
+//
+// Slices are encoded as repeated parameters. Fields tagged with `url` are path
+// parameters and are not included. Fields with a zero value are omitted unless
+// they have a `default` tag, in which case they are included so that the
+// default does not take their place. Nil pointers are always omitted.
+func EncodeURLValues(v any) (url.Values, error) {
+	_, query, err := encodeURLRequest(v, ParsePolicy{}, nil)
+	return query, err
+}
+
+// encodeURLRequest encodes v into its path parameters and query parameters.
+// Fields tagged with `url` are always path parameters. Fields tagged with
+// `json` and untagged fields are path parameters if they match one of
+// patternParams the same way that URLDecoder looks them up, and are query
+// parameters otherwise.
+func encodeURLRequest(v any, policy ParsePolicy, patternParams []string) (map[string]string, url.Values, error) {
+	params := make(map[string]string)
+	query := make(url.Values)
+
+	if _, ok := v.(None); ok || v == nil {
+		return params, query, nil
+	}
+
+	err := rfutil.EachStructField(v, func(rft reflect.StructField, rfv reflect.Value) error {
+		if rft.Type == ifMatchType {
+			return nil
+		}
+
+		source, key, tagOpts := fieldTag(rft)
+		if source == sourceJSON && key == "-" {
+			return nil
+		}
+
+		param, isParam := "", source == sourceURL
+		if isParam {
+			param = key
+		} else {
+			param, isParam = matchPatternParam(patternParams, source, key)
+		}
+
+		// Path parameters cannot be omitted, so only nil pointers are.
+		_, keep := rft.Tag.Lookup("default")
+		keep = keep || isParam
+		if rfv.IsZero() && (!keep || rfv.Kind() == reflect.Ptr) {
+			return nil
+		}
+
+		// format formats the field, or each element of it if it is a
+		// slice that URLDecoder decodes from repeated values.
+		format := func() ([]string, error) {
+			p, err := policy.withTagOptions(tagOpts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s", rft.Name)
+			}
+
+			elems := []reflect.Value{rfv}
+			if isParsableSlice(rft.Type) {
+				elems = make([]reflect.Value, rfv.Len())
+				for i := range elems {
+					elems[i] = rfv.Index(i)
+				}
+			}
+
+			vals := make([]string, len(elems))
+			for i, elem := range elems {
+				s, ok := rfutil.FormatToString(elem, p.parseOpts())
+				if !ok {
+					return nil, fmt.Errorf("field %s: unsupported type %s", rft.Name, rft.Type)
+				}
+				vals[i] = s
+			}
+			return vals, nil
+		}
+
+		if isParam {
+			if _, ok := params[param]; ok {
+				return nil // URLDecoder decodes both fields from it
+			}
+			vals, err := format()
+			if err != nil {
+				return err
+			}
+			params[param] = strings.Join(vals, ",")
+			return nil
+		}
+
+		if source == sourceJSON {
+			if rft.Type.Kind() == reflect.String {
+				query.Set(key, rfv.String())
+				return nil
+			}
+
+			b, err := json.Marshal(rfv.Interface())
+			if err != nil {
+				return errors.Wrapf(err, "field %s", rft.Name)
+			}
+			query.Set(key, string(b))
+			return nil
+		}
+
+		vals, err := format()
+		if err != nil {
+			return err
+		}
+		query[key] = vals
+		return nil
+	})
+
+	return params, query, err
+}
+
+// matchPatternParam returns the pattern parameter that URLDecoder decodes a
+// field with the given source and key from, if any. See lintField.readsParam.
+func matchPatternParam(patternParams []string, source fieldSource, key string) (string, bool) {
+	for _, param := range patternParams {
+		switch {
+		case source == sourceJSON && key == param,
+			source == sourceName && strings.EqualFold(key, param):
+			return param, true
+		}
+	}
+	return "", false
+}
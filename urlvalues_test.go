@@ -0,0 +1,155 @@
+package hrt
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type timeRequest struct {
+	Timeout  time.Duration  `query:"timeout"`
+	At       time.Time      `query:"at"`
+	Day      time.Time      `query:"day,layout=date"`
+	Unix     time.Time      `query:"unix,layout=unix"`
+	Milli    *time.Time     `query:"milli,layout=unixmilli"`
+	Limit    int            `query:"limit" default:"20"`
+	Active   bool           `query:"active"`
+	Status   itemStatus     `query:"status"`
+	Name     string         `json:"name"`
+	Internal string         `json:"-"`
+	Wait     *time.Duration `query:"wait"`
+	Tags     []string       `query:"tag"`
+}
+
+func TestURLDecoder_time(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  url.Values
+		expect result[timeRequest]
+	}{
+		{
+			name: "all",
+			input: url.Values{
+				"timeout": {"1h30m"},
+				"at":      {"2021-01-01T12:00:00Z"},
+				"day":     {"2021-02-03"},
+				"unix":    {"1600000000"},
+				"milli":   {"1600000000123"},
+			},
+			expect: okResult(timeRequest{
+				Timeout: 90 * time.Minute,
+				At:      time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+				Day:     time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC),
+				Unix:    time.Unix(1600000000, 0).UTC(),
+				Milli:   ptrTo(time.UnixMilli(1600000000123).UTC()),
+			}),
+		},
+		{
+			name:   "invalid duration",
+			input:  url.Values{"timeout": {"5"}},
+			expect: result[timeRequest]{error: `invalid duration: time: missing unit in duration "5"`},
+		},
+		{
+			name:   "invalid date",
+			input:  url.Values{"day": {"2021-02-03T00:00:00Z"}},
+			expect: result[timeRequest]{error: `invalid time: parsing time "2021-02-03T00:00:00Z": extra text: "T00:00:00Z"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got timeRequest
+			err := URLDecoder.Decode(&http.Request{Form: test.input}, &got)
+			res := combineResult(got, err)
+
+			if res.error != test.expect.error || (err == nil && !reflect.DeepEqual(res.value, test.expect.value)) {
+				t.Errorf("unexpected result:\n expected: %+v\n got: %+v", test.expect, res)
+			}
+		})
+	}
+}
+
+func TestEncodeURLValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  timeRequest
+		expect string
+	}{
+		{
+			name:   "zero",
+			input:  timeRequest{},
+			expect: "limit=0",
+		},
+		{
+			name: "all",
+			input: timeRequest{
+				Timeout:  90 * time.Minute,
+				At:       time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+				Day:      time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC),
+				Unix:     time.Unix(1600000000, 0).UTC(),
+				Milli:    ptrTo(time.UnixMilli(1600000000123).UTC()),
+				Limit:    5,
+				Active:   true,
+				Status:   "active",
+				Name:     "a b",
+				Internal: "secret",
+				Wait:     ptrTo(time.Second),
+				Tags:     []string{"a", "b"},
+			},
+			expect: "active=true&at=2021-01-01T12%3A00%3A00Z&day=2021-02-03&limit=5&milli=1600000000123" +
+				"&name=a+b&status=active&tag=a&tag=b&timeout=1h30m0s&unix=1600000000&wait=1s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := EncodeURLValues(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := q.Encode(); got != test.expect {
+				t.Errorf("unexpected query:\n expected: %s\n got: %s", test.expect, got)
+			}
+
+			// Decoding the query must give back the same value.
+			var got timeRequest
+			if err := URLDecoder.Decode(&http.Request{Form: q}, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			expect := test.input
+			expect.Internal = ""
+			if !reflect.DeepEqual(got, expect) {
+				t.Errorf("round trip mismatch:\n expected: %+v\n got: %+v", expect, got)
+			}
+		})
+	}
+}
+
+func TestSchemaGenerator_Parameters(t *testing.T) {
+	type request struct {
+		ID int `url:"id"`
+		timeRequest
+	}
+
+	g := NewSchemaGenerator()
+	params := g.Parameters(reflect.TypeFor[request]())
+
+	expect := `[
+		{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+		{"name": "timeout", "in": "query", "schema": {"type": "string", "format": "duration"}},
+		{"name": "at", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+		{"name": "day", "in": "query", "schema": {"type": "string", "format": "date"}},
+		{"name": "unix", "in": "query", "schema": {"type": "integer", "format": "int64"}},
+		{"name": "milli", "in": "query", "schema": {"type": "integer", "format": "int64"}},
+		{"name": "limit", "in": "query", "schema": {"type": "integer", "format": "int64", "default": 20}},
+		{"name": "active", "in": "query", "schema": {"type": "boolean"}},
+		{"name": "status", "in": "query", "schema": {"type": "string", "enum": ["active", "archived"]}},
+		{"name": "wait", "in": "query", "schema": {"type": "string", "format": "duration"}},
+		{"name": "tag", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}}
+	]`
+	assertJSONEqual(t, "parameters", expect, params)
+}